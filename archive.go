@@ -6,29 +6,37 @@ import (
 	"bytes"
 	"compress/bzip2"
 	"compress/gzip"
+	"context"
+	stderrors "errors"
 	"fmt"
 	"io"
 	"strings"
 
+	"github.com/tamada/wildcat/errors"
 	"github.com/tamada/wildcat/iowrapper"
 )
 
 func ConvertToArchiveEntry(entry Entry) (Entry, bool) {
+	return convertToArchiveEntry(entry, 0)
+}
+
+// convertToArchiveEntry converts the given entry into the archive entry, which expands at most the given bytes.
+func convertToArchiveEntry(entry Entry, limit int64) (Entry, bool) {
 	reader, err := entry.Open()
 	if err != nil {
 		return entry, false
 	}
 	gotKind, _ := reader.ParseFileType()
 	ext := gotKind.Extension
-	return createArchiveEntry(entry, ext)
+	return createArchiveEntry(entry, ext, limit)
 }
 
-func createArchiveEntry(entry Entry, ext string) (Entry, bool) {
+func createArchiveEntry(entry Entry, ext string, limit int64) (Entry, bool) {
 	switch ext {
 	case "gz", "bz2":
-		return wrapReaderAndTryAgain(entry, ext)
+		return wrapReaderAndTryAgain(entry, ext, limit)
 	case "jar", "zip":
-		return &ZipEntry{entry: entry}, true
+		return &ZipEntry{entry: entry, limit: limit}, true
 	case "tar":
 		return &TarEntry{entry: entry}, true
 	default:
@@ -36,9 +44,44 @@ func createArchiveEntry(entry Entry, ext string) (Entry, bool) {
 	}
 }
 
-func wrapReaderAndTryAgain(entry Entry, gotKind string) (Entry, bool) {
-	newEntry := &CompressedEntry{entry: entry}
-	return ConvertToArchiveEntry(newEntry)
+func wrapReaderAndTryAgain(entry Entry, gotKind string, limit int64) (Entry, bool) {
+	newEntry := &CompressedEntry{entry: entry, limit: limit}
+	return convertToArchiveEntry(newEntry, limit)
+}
+
+// errExpansionExceeded is the error of the archives and the compressed files expanding over ReadOptions.MaxExpansionSize.
+var errExpansionExceeded = stderrors.New("expanded size exceeds the limit")
+
+// expansionLimiter limits the total bytes read through its readers, for guarding against the zip bombs.
+type expansionLimiter struct {
+	limit     int64
+	remaining int64
+}
+
+// newExpansionLimiter creates the limiter of the given bytes, zero or less means no limit.
+func newExpansionLimiter(limit int64) *expansionLimiter {
+	return &expansionLimiter{limit: limit, remaining: limit}
+}
+
+func (el *expansionLimiter) wrap(in io.Reader) io.Reader {
+	if el.limit <= 0 {
+		return in
+	}
+	return &limitedReader{reader: in, limiter: el}
+}
+
+type limitedReader struct {
+	reader  io.Reader
+	limiter *expansionLimiter
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	n, err := lr.reader.Read(p)
+	lr.limiter.remaining -= int64(n)
+	if lr.limiter.remaining < 0 {
+		return n, fmt.Errorf("%w: %d bytes", errExpansionExceeded, lr.limiter.limit)
+	}
+	return n, err
 }
 
 func hasSuffix(fileName string, suffixes ...string) bool {
@@ -105,35 +148,54 @@ func (te *TarEntry) Open() (iowrapper.ReadCloseTypeParser, error) {
 }
 
 func (te *TarEntry) Count(generator Generator) *Either {
+	return te.CountContext(context.Background(), generator)
+}
+
+// CountContext counts the items in the receiver archive until the given context is done.
+func (te *TarEntry) CountContext(ctx context.Context, generator Generator) *Either {
 	reader, err := te.Open()
 	if err != nil {
 		return &Either{Err: err}
 	}
-	return countTarEntries(te, generator, tar.NewReader(reader))
+	return countTarEntries(ctx, te, generator, tar.NewReader(newContextReader(ctx, reader)))
 }
 
-func countTarEntries(entry Entry, generator Generator, tar *tar.Reader) *Either {
+func countTarEntries(ctx context.Context, entry Entry, generator Generator, tar *tar.Reader) *Either {
 	results := []*Result{}
 	index := entry.Index().Sub()
 	for {
+		if err := ctx.Err(); err != nil {
+			return &Either{Results: results, Err: err, Groups: []NameAndIndex{entry}}
+		}
 		header, err := tar.Next()
 		if err == io.EOF {
 			break
 		}
+		if err != nil {
+			return &Either{Err: archiveError(entry, err)}
+		}
 		name := fmt.Sprintf("%s!%s", entry.Name(), header.Name)
 		result, err := countArchiveItem(generator(), &tarItem{tar: tar, nameIndex: NewArgWithIndex(index, name)})
 		if err != nil {
-			return &Either{Err: err}
+			return &Either{Err: archiveError(entry, err)}
 		}
 		results = append(results, result)
 		index = index.Next()
 	}
-	return &Either{Results: results}
+	return &Either{Results: results, Groups: []NameAndIndex{entry}}
 }
 
+// archiveError creates the error for representing the given archive entry is corrupted or unsupported.
+func archiveError(entry Entry, err error) error {
+	return errors.NewError(errors.Archive, "extract", entry.Name(), entry.Index().String(), fmt.Errorf("%s: archive error: %w", entry.Name(), err))
+}
+
+// countArchiveItem counts the given item, the error is returned only if the archive expands over the limit.
 func countArchiveItem(counter Counter, item archiveItem) (*Result, error) {
-	item.Count(counter)
-	return &Result{nameIndex: item, counter: counter}, nil
+	if err := item.Count(counter); stderrors.Is(err, errExpansionExceeded) {
+		return nil, err
+	}
+	return &Result{nameIndex: item, counter: counter, archived: true}, nil
 }
 
 func (tf *tarItem) Count(counter Counter) error {
@@ -168,6 +230,7 @@ func createZipReader(in io.Reader) (*zip.Reader, error) {
 type zipItem struct {
 	nameIndex NameAndIndex
 	file      *zip.File
+	limiter   *expansionLimiter
 }
 
 func (zf *zipItem) Index() *Order {
@@ -184,11 +247,12 @@ func (zf *zipItem) Count(counter Counter) error {
 		return err
 	}
 	defer reader.Close()
-	return drainDataFromReader(reader, counter)
+	return drainDataFromReader(zf.limiter.wrap(reader), counter)
 }
 
 type ZipEntry struct {
 	entry Entry
+	limit int64
 }
 
 func (ze *ZipEntry) Index() *Order {
@@ -204,27 +268,35 @@ func (ze *ZipEntry) Open() (iowrapper.ReadCloseTypeParser, error) {
 }
 
 func (ze *ZipEntry) Count(generator Generator) *Either {
+	return ze.CountContext(context.Background(), generator)
+}
+
+// CountContext counts the items in the receiver archive until the given context is done.
+func (ze *ZipEntry) CountContext(ctx context.Context, generator Generator) *Either {
 	in, err := ze.Open()
 	if err != nil {
 		return &Either{Err: err}
 	}
-	rr, err := createZipReader(in)
+	rr, err := createZipReader(newContextReader(ctx, in))
 	if err != nil {
-		return &Either{Err: err}
+		return &Either{Err: archiveError(ze, err)}
 	}
-	return countZipEntries(ze, rr, generator)
+	return countZipEntries(ctx, ze, rr, generator, newExpansionLimiter(ze.limit))
 }
 
-func countZipEntries(entry Entry, rr *zip.Reader, generator Generator) *Either {
+func countZipEntries(ctx context.Context, entry Entry, rr *zip.Reader, generator Generator, limiter *expansionLimiter) *Either {
 	results := []*Result{}
 	index := entry.Index().Sub()
 	for _, f := range rr.File {
-		r, err := countArchiveItem(generator(), &zipItem{file: f, nameIndex: NewArgWithIndex(index, entry.Name())})
+		if err := ctx.Err(); err != nil {
+			return &Either{Results: results, Err: err, Groups: []NameAndIndex{entry}}
+		}
+		r, err := countArchiveItem(generator(), &zipItem{file: f, nameIndex: NewArgWithIndex(index, entry.Name()), limiter: limiter})
 		if err != nil {
-			return &Either{Err: err}
+			return &Either{Err: archiveError(entry, err)}
 		}
 		results = append(results, r)
 		index = index.Next()
 	}
-	return &Either{Results: results}
+	return &Either{Results: results, Groups: []NameAndIndex{entry}}
 }
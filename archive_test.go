@@ -1,7 +1,11 @@
 package wildcat
 
 import (
+	"context"
+	stderrors "errors"
 	"testing"
+
+	"github.com/tamada/wildcat/errors"
 )
 
 func TestArchives(t *testing.T) {
@@ -26,8 +30,11 @@ func TestArchives(t *testing.T) {
 		if rs.Size() != td.wontSize {
 			t.Errorf("archive (%s) size did not match, wont %d, got %d", td.giveFileName, td.wontSize, rs.Size())
 		}
-		if rs.total.Count(Lines) != td.wontTotalLines {
-			t.Errorf("archive (%s) total lines did not match, wont %d, got %d", td.giveFileName, td.wontTotalLines, rs.total.Count(Lines))
+		if rs.ArchivedSize() != td.wontSize {
+			t.Errorf("archive (%s) archived size did not match, wont %d, got %d", td.giveFileName, td.wontSize, rs.ArchivedSize())
+		}
+		if rs.TotalCount(Lines) != td.wontTotalLines {
+			t.Errorf("archive (%s) total lines did not match, wont %d, got %d", td.giveFileName, td.wontTotalLines, rs.TotalCount(Lines))
 		}
 		if rs.total.Count(Words) != td.wontTotalWords {
 			t.Errorf("archive (%s) total words did not match, wont %d, got %d", td.giveFileName, td.wontTotalWords, rs.total.Count(Words))
@@ -37,3 +44,29 @@ func TestArchives(t *testing.T) {
 		}
 	}
 }
+
+func TestMaxExpansionSize(t *testing.T) {
+	testdata := []struct {
+		giveFileName  string
+		giveLimit     int64
+		wontErrorSize int
+	}{
+		{"testdata/archives/wc.zip", 1000, 1},
+		{"testdata/archives/wc.tar.gz", 1000, 1},
+		{"testdata/archives/wc.tar.bz2", 1000, 1},
+		{"testdata/archives/humpty_dumpty.txt.gz", 100, 1},
+		{"testdata/archives/wc.zip", 10000, 0},
+		{"testdata/archives/wc.tar.gz", 0, 0},
+	}
+	for _, td := range testdata {
+		wc := New(WithMaxExpansionSize(td.giveLimit))
+		_, err := wc.Count(context.Background(), td.giveFileName)
+		ec, _ := err.(*errors.Center)
+		if td.wontErrorSize == 0 && err != nil || td.wontErrorSize > 0 && (ec == nil || ec.Size() != td.wontErrorSize) {
+			t.Errorf("%s (limit %d): wont %d errors, got %v", td.giveFileName, td.giveLimit, td.wontErrorSize, err)
+		}
+		if td.wontErrorSize > 0 && !stderrors.Is(err, errors.ErrArchive) {
+			t.Errorf("%s (limit %d): wont archive error, got %v", td.giveFileName, td.giveLimit, err)
+		}
+	}
+}
@@ -2,7 +2,9 @@ package wildcat
 
 import (
 	"bufio"
+	"context"
 	"io"
+	"net/http"
 	"path/filepath"
 	"strings"
 )
@@ -13,12 +15,50 @@ type ReadOptions struct {
 	NoIgnore  bool
 	NoExtract bool
 	AllFiles  bool
+	// Root restricts the files to read under the given directory, the empty string means no restriction.
+	// The relative paths (including the lines of the file lists) are resolved against Root, and reported relative to it.
+	// The files resolved outside of Root (e.g., by "..", or symbolic links) are reported as the permission errors.
+	Root string
+	// NoLocalFiles makes the empty Root forbid all of the paths on the local disk, instead of no restriction (e.g., for the server mode).
+	// The urls, and the entries given directly (e.g., the request bodies) are read regardless of this option.
+	NoLocalFiles bool
+	// MaxExpansionSize limits the bytes expanded from each archive or compressed file, zero or less means no limit.
+	// The archives expanding over the limit (e.g., zip bombs) are reported as the archive errors.
+	MaxExpansionSize int64
 }
 
 type RuntimeOptions struct {
 	ShowProgress bool
 	ThreadNumber int64
 	StoreContent bool
+	// StoreDir is the directory to store the contents of url targets by StoreContent, the empty string means the current directory.
+	// The existing files are not overwritten.
+	StoreDir string
+	// HTTPClient fetches the url targets, nil means http.DefaultClient.
+	// For example, the server mode gives the client refusing the private addresses.
+	HTTPClient *http.Client
+	// FailFast cancels the counting on the first error, it is the same as MaxErrors is 1.
+	FailFast bool
+	// MaxErrors cancels the counting after the given number of errors, zero or less means no limit.
+	MaxErrors int
+	// ProgressFactory creates the Progress for each counting to observe it, nil means NewTargetProgress(ShowProgress).
+	ProgressFactory func() Progress
+}
+
+// newProgress creates the Progress for a counting by ProgressFactory, or ShowProgress.
+func (opts *RuntimeOptions) newProgress() Progress {
+	if opts.ProgressFactory != nil {
+		return opts.ProgressFactory()
+	}
+	return NewTargetProgress(opts.ShowProgress)
+}
+
+// maxErrors returns the max number of errors before cancelling the counting.
+func (opts *RuntimeOptions) maxErrors() int {
+	if opts.FailFast {
+		return 1
+	}
+	return opts.MaxErrors
 }
 
 // Argf shows the command line arguments and stdin (if no command line arguments).
@@ -69,6 +109,27 @@ type Generator func() Counter
 // DefaultGenerator is the default generator for counting all (bytes, characters, words, and lines).
 var DefaultGenerator Generator = func() Counter { return NewCounter(All) }
 
+// contextReader is the reader which fails with the error of the given context after the context is done.
+// The context is checked before reading each chunk, so that the cancellation stops reading the large files and streams.
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func newContextReader(ctx context.Context, reader io.Reader) io.Reader {
+	if ctx.Done() == nil {
+		return reader
+	}
+	return &contextReader{ctx: ctx, reader: reader}
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.reader.Read(p)
+}
+
 func drainDataFromReader(in io.Reader, counter Counter) error {
 	reader := bufio.NewReader(in)
 	for {
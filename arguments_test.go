@@ -1,11 +1,16 @@
 package wildcat
 
 import (
+	"context"
+	stderrors "errors"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/tamada/wildcat/errors"
+	"github.com/tamada/wildcat/iowrapper"
 )
 
 func toStr(list []NameAndIndex) []string {
@@ -132,3 +137,61 @@ func TestStoreFile(t *testing.T) {
 		defer os.Remove(td.wontFileName)
 	}
 }
+
+func TestCreateTeeReader(t *testing.T) {
+	dir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(dir, "existing.txt"), []byte("existing\n"), 0644)
+	testdata := []struct {
+		giveURL   string
+		wontError bool
+	}{
+		{"https://example.com/new.txt", false},
+		{"https://example.com/existing.txt", true},
+		{"https://example.com/..", true},
+		{"https://example.com/", true},
+	}
+	for _, td := range testdata {
+		reader, err := createTeeReader(ioutil.NopCloser(strings.NewReader("content\n")), td.giveURL, dir)
+		if (err != nil) != td.wontError {
+			t.Errorf("%s: wont error %v, got %v", td.giveURL, td.wontError, err)
+		}
+		if reader != nil {
+			reader.Close()
+		}
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "existing.txt")); string(data) != "existing\n" {
+		t.Errorf("existing file was overwritten, got %s", string(data))
+	}
+}
+
+// endlessReader reads the endless lines, and calls the given cancel function at the given read.
+type endlessReader struct {
+	reads    int
+	cancelAt int
+	cancel   context.CancelFunc
+}
+
+func (er *endlessReader) Read(p []byte) (int, error) {
+	er.reads++
+	if er.reads == er.cancelAt {
+		er.cancel()
+	}
+	for i := range p {
+		p[i] = "a\n"[i%2]
+	}
+	return len(p), nil
+}
+
+func TestCountDefaultContextCancelsReading(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reader := &endlessReader{cancelAt: 3, cancel: cancel}
+	entry := &stdinEntry{index: NewOrder(), reader: iowrapper.NewReader(ioutil.NopCloser(reader))}
+	either := CountDefaultContext(ctx, entry, NewCounter(All))
+	if !stderrors.Is(either.Err, context.Canceled) || errors.KindOf(either.Err) != errors.Canceled {
+		t.Errorf("reading should be cancelled, got %v", either.Err)
+	}
+	if reader.reads != 3 {
+		t.Errorf("reading should stop just after the cancellation, but read %d times", reader.reads)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"crypto/sha256"
+	stderrors "errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenEnvName is the environment variable giving the api tokens separated by commas.
+const tokenEnvName = "WILDCAT_API_TOKENS"
+
+// errUnauthorized is the error of the requests without the valid token, which is responded as 401.
+var errUnauthorized = stderrors.New("unauthorized, the valid bearer token is required")
+
+// errRateLimited is the error of the requests over the rate limit of the token, which is responded as 429.
+var errRateLimited = stderrors.New("rate limit exceeded, try again later")
+
+// apiToken is the bearer token of the api, and its rate limit in requests per minute (zero means no limit).
+type apiToken struct {
+	token string
+	rate  int
+}
+
+// parseToken parses the given entry in "TOKEN[:RATE]" format, the default rate is used if the entry has no rate.
+func parseToken(entry string, defaultRate int) (*apiToken, error) {
+	token, rate := entry, defaultRate
+	if index := strings.LastIndex(entry, ":"); index >= 0 {
+		value, err := strconv.Atoi(entry[index+1:])
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("%s: rate limit must be zero or positive number", entry[index+1:])
+		}
+		token, rate = entry[:index], value
+	}
+	if token == "" {
+		return nil, fmt.Errorf("empty token")
+	}
+	return &apiToken{token: token, rate: rate}, nil
+}
+
+// loadTokens loads the api tokens from the environment variable, and the file given by --token-file.
+// The file has a token per line, and the empty lines and the lines starting with '#' are ignored.
+func (server *serverOptions) loadTokens() error {
+	entries := []string{}
+	if env := os.Getenv(tokenEnvName); env != "" {
+		entries = append(entries, strings.Split(env, ",")...)
+	}
+	if server.tokenFile != "" {
+		data, err := ioutil.ReadFile(server.tokenFile)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, strings.Split(string(data), "\n")...)
+	}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		token, err := parseToken(entry, server.rateLimit)
+		if err != nil {
+			return err
+		}
+		server.tokens = append(server.tokens, token)
+	}
+	return nil
+}
+
+// rateLimiter is the token bucket which allows the given requests per minute, and bursts up to the same number.
+type rateLimiter struct {
+	rate      float64
+	available float64
+	last      time.Time
+}
+
+func newRateLimiter(rate int) *rateLimiter {
+	return &rateLimiter{rate: float64(rate), available: float64(rate)}
+}
+
+// allow reports whether a request is allowed at the given time, and the time to wait for the next request if not allowed.
+func (rl *rateLimiter) allow(now time.Time) (bool, time.Duration) {
+	if rl.rate <= 0 {
+		return true, 0
+	}
+	if !rl.last.IsZero() {
+		rl.available = math.Min(rl.rate, rl.available+now.Sub(rl.last).Minutes()*rl.rate)
+	}
+	rl.last = now
+	if rl.available < 1 {
+		return false, time.Duration((1 - rl.available) / rl.rate * float64(time.Minute))
+	}
+	rl.available--
+	return true, 0
+}
+
+// authenticator checks the bearer tokens of the requests, and limits the rate of each token.
+// The tokens are kept as their hashes, so that looking up them does not leak the tokens by the timing.
+type authenticator struct {
+	mutex    sync.Mutex
+	limiters map[[sha256.Size]byte]*rateLimiter
+}
+
+// newAuthenticator creates the authenticator of the given tokens, this function returns nil if no tokens are given.
+func newAuthenticator(tokens []*apiToken) *authenticator {
+	if len(tokens) == 0 {
+		return nil
+	}
+	auth := &authenticator{limiters: map[[sha256.Size]byte]*rateLimiter{}}
+	for _, token := range tokens {
+		auth.limiters[sha256.Sum256([]byte(token.token))] = newRateLimiter(token.rate)
+	}
+	return auth
+}
+
+func bearerToken(req *http.Request) string {
+	header := req.Header.Get("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+func (auth *authenticator) check(token string, now time.Time) (time.Duration, error) {
+	auth.mutex.Lock()
+	defer auth.mutex.Unlock()
+	limiter, ok := auth.limiters[sha256.Sum256([]byte(token))]
+	if !ok || token == "" {
+		return 0, errUnauthorized
+	}
+	if ok, wait := limiter.allow(now); !ok {
+		return wait, errRateLimited
+	}
+	return 0, nil
+}
+
+// authenticate is the middleware which responds 401 for the requests without the valid token,
+// and 429 for the requests over the rate limit of the token.
+// The preflight requests of CORS are not authenticated, since the browsers send them without the credentials.
+func (auth *authenticator) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if auth == nil || req.Method == http.MethodOptions {
+			next.ServeHTTP(res, req)
+			return
+		}
+		wait, err := auth.check(bearerToken(req), time.Now())
+		switch {
+		case stderrors.Is(err, errUnauthorized):
+			res.Header().Set("WWW-Authenticate", `Bearer realm="wildcat"`)
+			respondError(res, &statusError{status: http.StatusUnauthorized, err: err})
+		case stderrors.Is(err, errRateLimited):
+			res.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			respondError(res, &statusError{status: http.StatusTooManyRequests, err: err})
+		default:
+			next.ServeHTTP(res, req)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseToken(t *testing.T) {
+	testdata := []struct {
+		giveEntry string
+		wontToken string
+		wontRate  int
+		wontError bool
+	}{
+		{"secret", "secret", 10, false},
+		{"secret:30", "secret", 30, false},
+		{"secret:0", "secret", 0, false},
+		{"secret:-1", "", 0, true},
+		{"secret:many", "", 0, true},
+		{":30", "", 0, true},
+	}
+	for _, td := range testdata {
+		token, err := parseToken(td.giveEntry, 10)
+		if (err != nil) != td.wontError {
+			t.Errorf("%s: wont error %v, got %v", td.giveEntry, td.wontError, err)
+		}
+		if err == nil && (token.token != td.wontToken || token.rate != td.wontRate) {
+			t.Errorf("%s: wont %s (%d), got %s (%d)", td.giveEntry, td.wontToken, td.wontRate, token.token, token.rate)
+		}
+	}
+}
+
+func TestLoadTokens(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tokens.txt")
+	ioutil.WriteFile(file, []byte("# comment\nfile-token:5\n\nother-token\n"), 0600)
+	os.Setenv(tokenEnvName, "env-token, env-token2:3")
+	defer os.Unsetenv(tokenEnvName)
+	opts := &serverOptions{tokenFile: file, rateLimit: 60}
+	if err := opts.loadTokens(); err != nil {
+		t.Fatalf("loading tokens: %v", err)
+	}
+	wonts := []apiToken{{"env-token", 60}, {"env-token2", 3}, {"file-token", 5}, {"other-token", 60}}
+	if len(opts.tokens) != len(wonts) {
+		t.Fatalf("tokens size did not match, wont %d, got %d", len(wonts), len(opts.tokens))
+	}
+	for i, wont := range wonts {
+		if *opts.tokens[i] != wont {
+			t.Errorf("tokens[%d] did not match, wont %v, got %v", i, wont, *opts.tokens[i])
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(2)
+	now := time.Now()
+	testdata := []struct {
+		giveTime  time.Time
+		wontAllow bool
+	}{
+		{now, true},
+		{now, true},
+		{now, false},
+		{now.Add(29 * time.Second), false},
+		{now.Add(31 * time.Second), true},
+		{now.Add(31 * time.Second), false},
+	}
+	for i, td := range testdata {
+		if allow, _ := limiter.allow(td.giveTime); allow != td.wontAllow {
+			t.Errorf("request %d: wont %v, got %v", i, td.wontAllow, allow)
+		}
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	router := createRestAPIServer(&serverOptions{tokens: []*apiToken{{"secret", 1}, {"unlimited", 0}}})
+	testdata := []struct {
+		giveMethod   string
+		giveToken    string
+		wontStatus   int
+		wontContains string
+	}{
+		{"POST", "", 401, `{"message":"unauthorized, the valid bearer token is required"}`},
+		{"POST", "Bearer wrong", 401, `unauthorized`},
+		{"POST", "Basic secret", 401, `unauthorized`},
+		{"OPTIONS", "", 204, ``},
+		{"POST", "Bearer secret", 200, `"lines":"1"`},
+		{"POST", "Bearer secret", 429, `{"message":"rate limit exceeded, try again later"}`},
+		{"POST", "bearer unlimited", 200, `"lines":"1"`},
+		{"POST", "Bearer unlimited", 200, `"lines":"1"`},
+	}
+	for _, td := range testdata {
+		req := httptest.NewRequest(td.giveMethod, "/wildcat/api/counts", strings.NewReader("hello world\n"))
+		if td.giveToken != "" {
+			req.Header.Set("Authorization", td.giveToken)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != td.wontStatus || !strings.Contains(rec.Body.String(), td.wontContains) {
+			t.Errorf("%s %s: wont %d %s, got %d %s", td.giveMethod, td.giveToken, td.wontStatus, td.wontContains, rec.Code, rec.Body.String())
+		}
+		if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: wont WWW-Authenticate header", td.giveToken)
+		}
+		if rec.Code == http.StatusTooManyRequests && rec.Header().Get("Retry-After") != "60" {
+			t.Errorf("%s: wont Retry-After 60, got %s", td.giveToken, rec.Header().Get("Retry-After"))
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The default CORS policy allows any origins to call the api without the credentials.
+const (
+	defaultCORSOrigins = "*"
+	defaultCORSMethods = "GET,POST,DELETE,OPTIONS"
+	defaultCORSHeaders = "Accept,Authorization,Content-Type"
+)
+
+// corsExposedHeaders are the response headers which the browsers expose to the scripts.
+const corsExposedHeaders = "Location,Retry-After"
+
+// corsPolicy is the CORS policy of the api given by --cors-* options.
+type corsPolicy struct {
+	origins     []string
+	methods     string
+	headers     string
+	credentials bool
+	maxAge      time.Duration
+}
+
+func newCORSPolicy(opts *serverOptions) *corsPolicy {
+	return &corsPolicy{
+		origins:     splitList(opts.corsOrigins),
+		methods:     strings.Join(splitList(opts.corsMethods), ","),
+		headers:     strings.Join(splitList(opts.corsHeaders), ","),
+		credentials: opts.corsCredentials,
+		maxAge:      opts.corsMaxAge,
+	}
+}
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// allowOrigin returns the value of Access-Control-Allow-Origin header for the given origin,
+// and false if the origin is not allowed.
+func (policy *corsPolicy) allowOrigin(origin string) (string, bool) {
+	if origin == "" {
+		return "", false
+	}
+	for _, allowed := range policy.origins {
+		if allowed == "*" {
+			return "*", true
+		}
+		if strings.EqualFold(allowed, origin) {
+			return origin, true
+		}
+	}
+	return "", false
+}
+
+// middleware sets the CORS headers to the responses of the allowed origins, and responds the preflight requests.
+func (policy *corsPolicy) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		header := res.Header()
+		header.Add("Vary", "Origin")
+		origin, ok := policy.allowOrigin(req.Header.Get("Origin"))
+		if ok {
+			header.Set("Access-Control-Allow-Origin", origin)
+			header.Set("Access-Control-Expose-Headers", corsExposedHeaders)
+			if policy.credentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+		if req.Method != http.MethodOptions || req.Header.Get("Access-Control-Request-Method") == "" {
+			next.ServeHTTP(res, req)
+			return
+		}
+		if ok {
+			header.Set("Access-Control-Allow-Methods", policy.methods)
+			header.Set("Access-Control-Allow-Headers", policy.headers)
+			if policy.maxAge > 0 {
+				header.Set("Access-Control-Max-Age", strconv.Itoa(int(policy.maxAge.Seconds())))
+			}
+		}
+		res.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// optionsHandler responds the OPTIONS requests which are not the preflight requests of CORS.
+func optionsHandler(res http.ResponseWriter, req *http.Request) {
+	res.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	restricted := &serverOptions{corsOrigins: "https://example.com", corsMethods: "POST", corsHeaders: "Content-Type",
+		corsCredentials: true, corsMaxAge: 10 * time.Minute}
+	testdata := []struct {
+		giveOpts          *serverOptions
+		giveMethod        string
+		giveURL           string
+		giveOrigin        string
+		giveRequestMethod string
+		wontStatus        int
+		wontOrigin        string
+		wontMethods       string
+		wontCredentials   string
+		wontMaxAge        string
+	}{
+		{&serverOptions{corsOrigins: defaultCORSOrigins, corsMethods: defaultCORSMethods}, "OPTIONS", "/wildcat/api/counts", "https://any.example.com", "POST", 204, "*", defaultCORSMethods, "", ""},
+		{&serverOptions{corsOrigins: defaultCORSOrigins}, "POST", "/wildcat/api/counts", "https://any.example.com", "", 200, "*", "", "", ""},
+		{restricted, "OPTIONS", "/wildcat/api/jobs/abc", "https://example.com", "GET", 204, "https://example.com", "POST", "true", "600"},
+		{restricted, "OPTIONS", "/wildcat/api/counts", "https://evil.example.com", "POST", 204, "", "", "", ""},
+		{restricted, "POST", "/wildcat/api/counts", "https://example.com", "", 200, "https://example.com", "", "true", ""},
+		{restricted, "POST", "/wildcat/api/counts", "https://evil.example.com", "", 200, "", "", "", ""},
+		{restricted, "POST", "/wildcat/api/counts", "", "", 200, "", "", "", ""},
+		{&serverOptions{}, "OPTIONS", "/wildcat/api/counts", "https://example.com", "POST", 204, "", "", "", ""},
+	}
+	for _, td := range testdata {
+		req := httptest.NewRequest(td.giveMethod, td.giveURL, strings.NewReader("hello world\n"))
+		if td.giveOrigin != "" {
+			req.Header.Set("Origin", td.giveOrigin)
+		}
+		if td.giveRequestMethod != "" {
+			req.Header.Set("Access-Control-Request-Method", td.giveRequestMethod)
+		}
+		rec := httptest.NewRecorder()
+		createRestAPIServer(td.giveOpts).ServeHTTP(rec, req)
+		header := rec.Header()
+		if rec.Code != td.wontStatus {
+			t.Errorf("%s %s from %s: status code did not match, wont %d, got %d", td.giveMethod, td.giveURL, td.giveOrigin, td.wontStatus, rec.Code)
+		}
+		gots := []string{header.Get("Access-Control-Allow-Origin"), header.Get("Access-Control-Allow-Methods"), header.Get("Access-Control-Allow-Credentials"), header.Get("Access-Control-Max-Age")}
+		wonts := []string{td.wontOrigin, td.wontMethods, td.wontCredentials, td.wontMaxAge}
+		for i := range wonts {
+			if gots[i] != wonts[i] {
+				t.Errorf("%s %s from %s: headers did not match, wont %v, got %v", td.giveMethod, td.giveURL, td.giveOrigin, wonts, gots)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tamada/wildcat"
+	"github.com/tamada/wildcat/logger"
+)
+
+// The default limits of the jobs api.
+const (
+	defaultJobTTL     = 10 * time.Minute
+	defaultMaxJobs    = 16
+	defaultJobTimeout = time.Hour
+)
+
+// errTooManyJobs is the error of the jobs over --max-jobs, which is responded as 503.
+var errTooManyJobs = stderrors.New("too many running jobs, try again later")
+
+// errShuttingDown is the error of the jobs created during the graceful shutdown, which is responded as 503.
+var errShuttingDown = stderrors.New("server is shutting down")
+
+const (
+	jobRunning   = "running"
+	jobCompleted = "completed"
+	jobFailed    = "failed"
+)
+
+// jobProgress counts the targets of the job through the Progress interface.
+type jobProgress struct {
+	wildcat.ContextProgress
+	targets int64
+	done    int64
+}
+
+func (jp *jobProgress) UpdateTarget() {
+	jp.UpdateTargetContext(context.Background())
+}
+
+func (jp *jobProgress) UpdateTargetContext(ctx context.Context) error {
+	if err := jp.ContextProgress.UpdateTargetContext(ctx); err != nil {
+		return err
+	}
+	atomic.AddInt64(&jp.targets, 1)
+	return nil
+}
+
+func (jp *jobProgress) Done() {
+	atomic.AddInt64(&jp.done, 1)
+	jp.ContextProgress.Done()
+}
+
+// job is the counting running in the background.
+type job struct {
+	mutex    sync.Mutex
+	id       string
+	status   string
+	created  time.Time
+	finished time.Time
+	progress *jobProgress
+	cancel   context.CancelFunc
+	done     chan struct{}
+	deleted  bool
+	opts     *requestOptions
+	rs       *wildcat.ResultSet
+	err      error
+}
+
+// jobStatus is the json representation of job.
+type jobStatus struct {
+	ID       string     `json:"id"`
+	Status   string     `json:"status"`
+	Targets  int64      `json:"targets"`
+	Done     int64      `json:"done"`
+	Created  time.Time  `json:"created"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Message  string     `json:"message,omitempty"`
+}
+
+func (j *job) finish(rs *wildcat.ResultSet, err error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.rs, j.err = rs, err
+	j.finished = time.Now()
+	j.status = jobCompleted
+	if isError(err) {
+		j.status = jobFailed
+	}
+	close(j.done)
+}
+
+func (j *job) toStatus() *jobStatus {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	status := &jobStatus{ID: j.id, Status: j.status, Created: j.created,
+		Targets: atomic.LoadInt64(&j.progress.targets), Done: atomic.LoadInt64(&j.progress.done)}
+	if j.status != jobRunning {
+		finished := j.finished
+		status.Finished = &finished
+	}
+	if j.status == jobFailed {
+		status.Message = j.err.Error()
+	}
+	return status
+}
+
+func (j *job) isRunning() bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.status == jobRunning
+}
+
+func (j *job) isDeleted() bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.deleted
+}
+
+func (j *job) markDeleted() {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.deleted = true
+}
+
+// isExpired checks the job is finished, and is deleted or kept over the given ttl.
+func (j *job) isExpired(now time.Time, ttl time.Duration) bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.status != jobRunning && (j.deleted || now.Sub(j.finished) > ttl)
+}
+
+// jobStore is the in-memory store of the jobs.
+// The finished jobs are removed after the TTL, by the periodic sweep, or on the next access to the store.
+// The deleted jobs are hidden at once, however, the running ones are kept (and counted for --max-jobs) until they finish.
+type jobStore struct {
+	mutex  sync.Mutex
+	jobs   map[string]*job
+	ttl    time.Duration
+	now    func() time.Time
+	closed bool
+}
+
+func newJobStore(ttl time.Duration) *jobStore {
+	if ttl <= 0 {
+		ttl = defaultJobTTL
+	}
+	return &jobStore{jobs: map[string]*job{}, ttl: ttl, now: time.Now}
+}
+
+func (store *jobStore) put(j *job) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.cleanup()
+	store.jobs[j.id] = j
+}
+
+func (store *jobStore) get(id string) (*job, bool) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.cleanup()
+	j, ok := store.jobs[id]
+	if !ok || j.isDeleted() {
+		return nil, false
+	}
+	return j, true
+}
+
+// remove marks the job of the given id as deleted, and removes it from the store unless it is running.
+// The running job is removed when it finishes, by the caller cancelling it.
+func (store *jobStore) remove(id string) (*job, bool) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	j, ok := store.jobs[id]
+	if !ok || j.isDeleted() {
+		return nil, false
+	}
+	j.markDeleted()
+	store.cleanup()
+	return j, true
+}
+
+// start puts the given job, unless the max number of the jobs are running, or the store is closed by the shutdown.
+// The max less equals than 0 means no limit.
+func (store *jobStore) start(j *job, max int) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.cleanup()
+	if store.closed {
+		return errShuttingDown
+	}
+	if max > 0 && store.countRunning() >= max {
+		return errTooManyJobs
+	}
+	store.jobs[j.id] = j
+	return nil
+}
+
+// sweep removes the expired jobs, and the finished jobs deleted while running.
+func (store *jobStore) sweep() {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.cleanup()
+}
+
+// sweepPeriodically sweeps the store in every TTL until the given channel is closed,
+// since the finished jobs are kept while no one accesses the store.
+func (store *jobStore) sweepPeriodically(stop <-chan struct{}) {
+	ticker := time.NewTicker(store.ttl)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			store.sweep()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// cancelAll closes the store for the new jobs, cancels the running jobs, and waits for them until the given context is done.
+// This method is called on the graceful shutdown.
+func (store *jobStore) cancelAll(ctx context.Context) {
+	store.mutex.Lock()
+	store.closed = true
+	jobs := []*job{}
+	for _, j := range store.jobs {
+		if j.isRunning() {
+			j.cancel()
+			jobs = append(jobs, j)
+		}
+	}
+	store.mutex.Unlock()
+	for _, j := range jobs {
+		select {
+		case <-j.done:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// running returns the number of the running jobs.
+func (store *jobStore) running() int {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.countRunning()
+}
+
+// countRunning counts the running jobs, the caller must hold the lock.
+func (store *jobStore) countRunning() int {
+	count := 0
+	for _, j := range store.jobs {
+		if j.isRunning() {
+			count++
+		}
+	}
+	return count
+}
+
+// cleanup removes the expired jobs, the caller must hold the lock.
+func (store *jobStore) cleanup() {
+	now := store.now()
+	for id, j := range store.jobs {
+		if j.isExpired(now, store.ttl) {
+			delete(store.jobs, id)
+		}
+	}
+}
+
+func newJobID() string {
+	data := make([]byte, 16)
+	rand.Read(data)
+	return hex.EncodeToString(data)
+}
+
+// createJob starts the counting of the request in the background, and responds the status of the job with 202 Accepted.
+// The request body is spooled into a temporary file before responding, since it is closed after the response.
+// Over --max-jobs running jobs, the request is responded as 503, and each job is canceled after --job-timeout.
+func (server *restServer) createJob(res http.ResponseWriter, req *http.Request) {
+	opts, err := server.parseRequestOptions(req)
+	if err != nil {
+		respondError(res, err)
+		return
+	}
+	body, err := spoolBody(req.Body)
+	if err != nil {
+		respondError(res, fmt.Errorf("reading request body: %w", err))
+		return
+	}
+	ctx, cancel := newJobContext(server.opts.jobTimeout)
+	j := &job{id: newJobID(), status: jobRunning, created: time.Now(), cancel: cancel, done: make(chan struct{}), opts: opts,
+		progress: &jobProgress{ContextProgress: wildcat.NewTargetProgress(false)}}
+	opts.runtime.ProgressFactory = func() wildcat.Progress { return j.progress }
+	jobReq := req.Clone(ctx)
+	jobReq.Body = body
+	if err := server.jobs.start(j, server.opts.maxJobs); err != nil {
+		cancel()
+		body.Close()
+		res.Header().Set("Retry-After", "1")
+		respondError(res, &statusError{status: http.StatusServiceUnavailable, err: err})
+		return
+	}
+	go server.runJob(j, jobReq)
+	logger.Infof("job %s: started", j.id)
+	res.Header().Set("Location", fmt.Sprintf("/wildcat/api/jobs/%s", j.id))
+	respondJSON(res, http.StatusAccepted, j.toStatus())
+}
+
+// spooledBody is the request body copied into a temporary file, which is removed on closing.
+type spooledBody struct {
+	*os.File
+}
+
+func (sb *spooledBody) Close() error {
+	err := sb.File.Close()
+	os.Remove(sb.Name())
+	return err
+}
+
+// spoolBody copies the given request body into a temporary file, for keeping the bodies of the queued jobs out of memory.
+func spoolBody(body io.Reader) (io.ReadCloser, error) {
+	file, err := ioutil.TempFile("", "wildcat-job-")
+	if err != nil {
+		return nil, err
+	}
+	spooled := &spooledBody{File: file}
+	if _, err := io.Copy(file, body); err != nil {
+		spooled.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		spooled.Close()
+		return nil, err
+	}
+	return spooled, nil
+}
+
+// newJobContext returns the context of a job, which is independent of the request creating the job.
+func newJobContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(context.Background(), timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+func (server *restServer) runJob(j *job, req *http.Request) {
+	defer j.cancel()
+	defer req.Body.Close()
+	rs, err := server.countFunc(req)(nil, req, j.opts)
+	server.metrics.observeResults(rs)
+	if rs != nil {
+		j.opts.printer.applyTo(rs)
+	}
+	j.finish(rs, err)
+	server.jobs.sweep()
+	logger.Infof("job %s: %s", j.id, j.toStatus().Status)
+}
+
+func (server *restServer) findJob(res http.ResponseWriter, req *http.Request) (*job, bool) {
+	id := mux.Vars(req)["id"]
+	j, ok := server.jobs.get(id)
+	if !ok {
+		respondError(res, &statusError{status: http.StatusNotFound, err: fmt.Errorf("%s: job not found", id)})
+	}
+	return j, ok
+}
+
+func (server *restServer) jobStatus(res http.ResponseWriter, req *http.Request) {
+	if j, ok := server.findJob(res, req); ok {
+		respondJSON(res, http.StatusOK, j.toStatus())
+	}
+}
+
+// jobResults responds the results of the finished job in the format negotiated by the request.
+// The sort order, the subtotals, and the statistics are given at creating the job.
+func (server *restServer) jobResults(res http.ResponseWriter, req *http.Request) {
+	j, ok := server.findJob(res, req)
+	if !ok {
+		return
+	}
+	format, err := negotiateFormat(req)
+	if err != nil {
+		respondError(res, err)
+		return
+	}
+	status := j.toStatus()
+	if status.Status == jobRunning {
+		respondError(res, &statusError{status: http.StatusConflict, err: fmt.Errorf("%s: job is running", j.id)})
+		return
+	}
+	respond(j.rs, j.err, res, format, j.opts.sizer())
+}
+
+// deleteJob cancels the job if it is running, and removes it from the store.
+// The canceled job is counted for --max-jobs until its counting stops.
+func (server *restServer) deleteJob(res http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)["id"]
+	j, ok := server.jobs.remove(id)
+	if !ok {
+		respondError(res, &statusError{status: http.StatusNotFound, err: fmt.Errorf("%s: job not found", id)})
+		return
+	}
+	j.cancel()
+	logger.Infof("job %s: deleted", id)
+	res.WriteHeader(http.StatusNoContent)
+}
+
+func respondJSON(res http.ResponseWriter, statusCode int, value interface{}) {
+	data, _ := json.Marshal(value)
+	res.Header().Set("Content-Type", jsonFormat.contentType())
+	respondImpl(res, statusCode, data)
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func serveJobRequest(router *mux.Router, method, url string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// waitJob waits for the job of the given location to finish, and returns its status through the api.
+func waitJob(t *testing.T, server *restServer, router *mux.Router, location string) *jobStatus {
+	j, ok := server.jobs.get(path.Base(location))
+	if !ok {
+		t.Fatalf("%s: job not found", location)
+	}
+	select {
+	case <-j.done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("%s: job did not finish in time", location)
+	}
+	status := &jobStatus{}
+	rec := serveJobRequest(router, "GET", location, "")
+	if err := json.Unmarshal(rec.Body.Bytes(), status); err != nil {
+		t.Fatalf("%s: invalid status: %s", location, rec.Body.String())
+	}
+	return status
+}
+
+func TestJobs(t *testing.T) {
+	content, _ := ioutil.ReadFile("../../testdata/wc/humpty_dumpty.txt")
+	testdata := []struct {
+		giveURL      string
+		wontStatus   string
+		wontContains string
+	}{
+		{"/wildcat/api/jobs?file-name=humpty.txt", jobCompleted, `"results":[{"filename":"humpty.txt","lines":"4","words":"26","characters":"142","bytes":"142"}]`},
+		{"/wildcat/api/jobs?readAs=file-list", jobFailed, `outside of the root directory`},
+	}
+	server := newRestServer(&serverOptions{})
+	router := server.router()
+	for _, td := range testdata {
+		rec := serveJobRequest(router, "POST", td.giveURL, string(content))
+		location := rec.Header().Get("Location")
+		if rec.Code != http.StatusAccepted || !strings.HasPrefix(location, "/wildcat/api/jobs/") {
+			t.Errorf("%s: wont 202 with location, got %d (%s)", td.giveURL, rec.Code, location)
+			continue
+		}
+		status := waitJob(t, server, router, location)
+		if status.Status != td.wontStatus || status.Finished == nil {
+			t.Errorf("%s: status did not match, wont %s, got %s", td.giveURL, td.wontStatus, status.Status)
+		}
+		if rec := serveJobRequest(router, "GET", location+"/results", ""); !strings.Contains(rec.Body.String(), td.wontContains) {
+			t.Errorf("%s: results did not match,\nwont %s,\ngot  %s", td.giveURL, td.wontContains, rec.Body.String())
+		}
+		if rec := serveJobRequest(router, "DELETE", location, ""); rec.Code != http.StatusNoContent {
+			t.Errorf("%s: delete status code did not match, wont 204, got %d", td.giveURL, rec.Code)
+		}
+		if rec := serveJobRequest(router, "GET", location, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: status code after deletion did not match, wont 404, got %d", td.giveURL, rec.Code)
+		}
+	}
+}
+
+func TestRunningJob(t *testing.T) {
+	remote := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+	}))
+	defer remote.Close()
+	router := createRestAPIServer(&serverOptions{allowPrivateURLs: true})
+	req := httptest.NewRequest("POST", "/wildcat/api/jobs", strings.NewReader(`{"targets":["`+remote.URL+`/slow.txt"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	location := rec.Header().Get("Location")
+	if rec := serveJobRequest(router, "GET", location+"/results", ""); rec.Code != http.StatusConflict {
+		t.Errorf("results of the running job: wont 409, got %d", rec.Code)
+	}
+	if rec := serveJobRequest(router, "DELETE", location, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("deleting the running job: wont 204, got %d", rec.Code)
+	}
+	if rec := serveJobRequest(router, "DELETE", location, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("deleting the deleted job: wont 404, got %d", rec.Code)
+	}
+}
+
+func TestJobStoreTTL(t *testing.T) {
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	store := newJobStore(time.Minute)
+	store.now = func() time.Time { return now }
+	running := &job{id: "running", status: jobRunning}
+	finished := &job{id: "finished", status: jobCompleted, finished: now}
+	store.put(running)
+	store.put(finished)
+	if _, ok := store.get("finished"); !ok {
+		t.Errorf("finished job should be kept within the ttl")
+	}
+	now = now.Add(2 * time.Minute)
+	if _, ok := store.get("finished"); ok {
+		t.Errorf("finished job should be removed after the ttl")
+	}
+	if _, ok := store.get("running"); !ok {
+		t.Errorf("running job should not be removed")
+	}
+}
+
+func TestJobLimits(t *testing.T) {
+	remote := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+	}))
+	defer remote.Close()
+	server := newRestServer(&serverOptions{maxJobs: 1, jobTimeout: 100 * time.Millisecond, allowPrivateURLs: true})
+	router := server.router()
+	body := `{"targets":["` + remote.URL + `/slow.txt"]}`
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/wildcat/api/jobs", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	first := post()
+	if first.Code != http.StatusAccepted {
+		t.Fatalf("first job: wont 202, got %d", first.Code)
+	}
+	if rec := post(); rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("job over --max-jobs: wont 503 with Retry-After, got %d", rec.Code)
+	}
+	if status := waitJob(t, server, router, first.Header().Get("Location")); status.Status != jobFailed {
+		t.Errorf("job over --job-timeout: wont %s, got %s", jobFailed, status.Status)
+	}
+	if rec := post(); rec.Code != http.StatusAccepted {
+		t.Errorf("job after the running job finished: wont 202, got %d", rec.Code)
+	}
+}
+
+func TestDeletedRunningJob(t *testing.T) {
+	store := newJobStore(time.Minute)
+	running := &job{id: "running", status: jobRunning, cancel: func() {}, done: make(chan struct{})}
+	if err := store.start(running, 1); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if _, ok := store.remove("running"); !ok {
+		t.Errorf("running job should be removed")
+	}
+	if _, ok := store.get("running"); ok {
+		t.Errorf("deleted job should be hidden")
+	}
+	if err := store.start(&job{id: "next", status: jobRunning}, 1); err != errTooManyJobs {
+		t.Errorf("deleted job should be counted for --max-jobs until it finishes, got %v", err)
+	}
+	running.finish(nil, nil)
+	store.sweep()
+	if store.running() != 0 || len(store.jobs) != 0 {
+		t.Errorf("deleted job should be removed after it finishes, got %d jobs", len(store.jobs))
+	}
+}
+
+func TestJobStoreSweepPeriodically(t *testing.T) {
+	store := newJobStore(10 * time.Millisecond)
+	store.put(&job{id: "finished", status: jobCompleted, finished: time.Now()})
+	stop := make(chan struct{})
+	defer close(stop)
+	go store.sweepPeriodically(stop)
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		store.mutex.Lock()
+		size := len(store.jobs)
+		store.mutex.Unlock()
+		if size == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("expired job should be removed without accessing the store")
+}
+
+func TestCancelJobsOnShutdown(t *testing.T) {
+	remote := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+	}))
+	defer remote.Close()
+	server := newRestServer(&serverOptions{allowPrivateURLs: true})
+	router := server.router()
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/wildcat/api/jobs", strings.NewReader(`{"targets":["`+remote.URL+`/slow.txt"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	rec := post()
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("job: wont 202, got %d", rec.Code)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	server.jobs.cancelAll(ctx)
+	if status := waitJob(t, server, router, rec.Header().Get("Location")); status.Status != jobFailed {
+		t.Errorf("job canceled by the shutdown: wont %s, got %s", jobFailed, status.Status)
+	}
+	if rec := post(); rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), errShuttingDown.Error()) {
+		t.Errorf("job during the shutdown: wont 503, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestSpoolBody(t *testing.T) {
+	body, err := spoolBody(strings.NewReader("hello world\n"))
+	if err != nil {
+		t.Fatalf("spoolBody: %v", err)
+	}
+	name := body.(*spooledBody).Name()
+	if data, _ := ioutil.ReadAll(body); string(data) != "hello world\n" {
+		t.Errorf("spooled body did not match, got %s", string(data))
+	}
+	body.Close()
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("%s: spooled file should be removed on closing", name)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/tamada/wildcat/logger"
+)
+
+// The default limits of the server mode.
+const (
+	defaultMaxBodySize      = 100 << 20
+	defaultMaxExpansionSize = 1 << 30
+	defaultMaxRequests      = 64
+	defaultRequestTimeout   = 5 * time.Minute
+	defaultShutdownTimeout  = 30 * time.Second
+	readHeaderTimeout       = 10 * time.Second
+	idleTimeout             = 2 * time.Minute
+)
+
+// errBodyTooLarge is the error of the request bodies over --max-body-size, which is responded as 413.
+var errBodyTooLarge = stderrors.New("request body too large")
+
+// errTooManyRequests is the error of the requests over --max-requests, which is responded as 503.
+var errTooManyRequests = stderrors.New("too many requests, try again later")
+
+var sizeUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+}
+
+// byteSize is the flag value of the size in bytes, which accepts the units (e.g., 512K, 100M, and 1G).
+type byteSize int64
+
+func (bs *byteSize) Set(value string) error {
+	number := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(value)), "B")
+	unit := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(number, u.suffix) {
+			number, unit = strings.TrimSuffix(number, u.suffix), u.size
+		}
+	}
+	size, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || size < 0 {
+		return fmt.Errorf("%s: invalid size", value)
+	}
+	if size > math.MaxInt64/unit {
+		return fmt.Errorf("%s: too large size", value)
+	}
+	*bs = byteSize(size * unit)
+	return nil
+}
+
+func (bs *byteSize) String() string {
+	for i := len(sizeUnits) - 1; i >= 0; i-- {
+		if *bs != 0 && int64(*bs)%sizeUnits[i].size == 0 {
+			return fmt.Sprintf("%d%s", int64(*bs)/sizeUnits[i].size, sizeUnits[i].suffix)
+		}
+	}
+	return strconv.FormatInt(int64(*bs), 10)
+}
+
+func (bs *byteSize) Type() string {
+	return "size"
+}
+
+// limitedBody is the request body which fails with errBodyTooLarge after reading the given bytes.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (lb *limitedBody) Read(p []byte) (int, error) {
+	if int64(len(p)) > lb.remaining+1 {
+		p = p[:lb.remaining+1]
+	}
+	n, err := lb.ReadCloser.Read(p)
+	if int64(n) <= lb.remaining {
+		lb.remaining -= int64(n)
+		return n, err
+	}
+	n, lb.remaining = int(lb.remaining), 0
+	return n, errBodyTooLarge
+}
+
+// limitRequests is the middleware which limits the number of concurrent requests,
+// the size of the request bodies, and the time of each request by the server options.
+func (server *restServer) limitRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if !server.acquire() {
+			res.Header().Set("Retry-After", "1")
+			respondError(res, &statusError{status: http.StatusServiceUnavailable, err: errTooManyRequests})
+			return
+		}
+		defer server.release()
+		if size := int64(server.opts.maxBodySize); size > 0 {
+			if req.ContentLength > size {
+				respondError(res, fmt.Errorf("%w (max %s)", errBodyTooLarge, &server.opts.maxBodySize))
+				return
+			}
+			req.Body = &limitedBody{ReadCloser: req.Body, remaining: size}
+		}
+		if timeout := server.opts.requestTimeout; timeout > 0 {
+			ctx, cancel := context.WithTimeout(req.Context(), timeout)
+			defer cancel()
+			req = req.WithContext(ctx)
+		}
+		next.ServeHTTP(res, req)
+	})
+}
+
+func (server *restServer) acquire() bool {
+	if server.requests == nil {
+		return true
+	}
+	select {
+	case server.requests <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (server *restServer) release() {
+	if server.requests != nil {
+		<-server.requests
+	}
+}
+
+func newRequestSemaphore(max int) chan struct{} {
+	if max <= 0 {
+		return nil
+	}
+	return make(chan struct{}, max)
+}
+
+func (server *serverOptions) newHTTPServer(handler http.Handler) *http.Server {
+	httpServer := &http.Server{
+		Addr:              server.address(),
+		Handler:           handler,
+		ReadHeaderTimeout: readHeaderTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+	if server.requestTimeout > 0 {
+		httpServer.ReadTimeout = server.requestTimeout
+		httpServer.WriteTimeout = server.requestTimeout + readHeaderTimeout
+	}
+	return httpServer
+}
+
+// shutdownOnSignal shuts down the given server gracefully on SIGINT or SIGTERM, and closes the done channel after the shutdown.
+func (server *serverOptions) shutdownOnSignal(rest *restServer, httpServer *http.Server, done chan<- struct{}) {
+	defer close(done)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	sig := <-signals
+	logger.Infof("%s: shutting down, waiting for the requests in flight", sig)
+	server.shutdown(rest, httpServer)
+}
+
+// shutdown marks the rest server as not ready before closing the listeners, cancels the running jobs,
+// and drains the requests in flight and the jobs until --shutdown-timeout.
+func (server *serverOptions) shutdown(rest *restServer, httpServer *http.Server) {
+	rest.shutdown()
+	ctx, cancel := context.WithTimeout(context.Background(), server.shutdownTimeout)
+	defer cancel()
+	jobsDone := make(chan struct{})
+	go func() {
+		defer close(jobsDone)
+		rest.jobs.cancelAll(ctx)
+	}()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		logger.Warnf("shutdown: %s", err)
+		httpServer.Close()
+	}
+	<-jobsDone
+}
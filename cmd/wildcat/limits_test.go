@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestByteSize(t *testing.T) {
+	testdata := []struct {
+		giveString string
+		wontSize   int64
+		wontString string
+		wontError  bool
+	}{
+		{"1024", 1024, "1K", false},
+		{"512K", 512 << 10, "512K", false},
+		{"100m", 100 << 20, "100M", false},
+		{"1GB", 1 << 30, "1G", false},
+		{"1000", 1000, "1000", false},
+		{"0", 0, "0", false},
+		{"-1", 0, "", true},
+		{"1X", 0, "", true},
+		{"8388607T", 8388607 << 40, "8388607T", false},
+		{"8388608T", 0, "", true},
+		{"9999999999G", 0, "", true},
+	}
+	for _, td := range testdata {
+		var size byteSize
+		err := size.Set(td.giveString)
+		if (err != nil) != td.wontError {
+			t.Errorf("%s: wont error %v, got %v", td.giveString, td.wontError, err)
+		}
+		if err == nil && (int64(size) != td.wontSize || size.String() != td.wontString) {
+			t.Errorf("%s: wont %d (%s), got %d (%s)", td.giveString, td.wontSize, td.wontString, size, size.String())
+		}
+	}
+}
+
+// chunkedReader hides the length of the request body from httptest.NewRequest.
+type chunkedReader struct {
+	io.Reader
+}
+
+func TestRequestLimits(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+	}))
+	defer slow.Close()
+	content, _ := ioutil.ReadFile("../../testdata/archives/wc.zip")
+	archive := bytes.NewBuffer([]byte{})
+	writer := multipart.NewWriter(archive)
+	part, _ := writer.CreateFormFile("file", "wc.zip")
+	part.Write(content)
+	writer.Close()
+	testdata := []struct {
+		giveOpts        *serverOptions
+		giveContentType string
+		giveBody        io.Reader
+		wontStatus      int
+		wontContains    string
+	}{
+		{&serverOptions{maxBodySize: 10}, "text/plain", strings.NewReader("hello world\n"), 413, `{"message":"request body too large (max 10)"}`},
+		{&serverOptions{maxBodySize: 10}, "text/plain", &chunkedReader{strings.NewReader("hello world\n")}, 413, `request body too large`},
+		{&serverOptions{maxBodySize: 12}, "text/plain", &chunkedReader{strings.NewReader("hello world\n")}, 200, `"lines":"1","words":"2"`},
+		{&serverOptions{requestTimeout: 100 * time.Millisecond, allowPrivateURLs: true}, "application/json", strings.NewReader(`{"targets":["` + slow.URL + `/slow.txt"]}`), 503, `context deadline exceeded`},
+		{&serverOptions{maxExpansionSize: 1000}, writer.FormDataContentType(), bytes.NewReader(archive.Bytes()), 400, `wc.zip: archive error: expanded size exceeds the limit: 1000 bytes`},
+		{&serverOptions{maxExpansionSize: 10000}, writer.FormDataContentType(), bytes.NewReader(archive.Bytes()), 200, `{"filename":"total","lines":"78"`},
+	}
+	for _, td := range testdata {
+		req := httptest.NewRequest("POST", "/wildcat/api/counts", td.giveBody)
+		req.Header.Set("Content-Type", td.giveContentType)
+		rec := httptest.NewRecorder()
+		createRestAPIServer(td.giveOpts).ServeHTTP(rec, req)
+		if rec.Code != td.wontStatus {
+			t.Errorf("%v: status code did not match, wont %d, got %d", td.giveOpts, td.wontStatus, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), td.wontContains) {
+			t.Errorf("%v: response body did not match,\nwont %s,\ngot  %s", td.giveOpts, td.wontContains, rec.Body.String())
+		}
+	}
+}
+
+func TestMaxRequests(t *testing.T) {
+	server := &restServer{opts: &serverOptions{maxRequests: 1}, requests: newRequestSemaphore(1)}
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := server.limitRequests(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		close(started)
+		<-release
+	}))
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/wildcat/api/counts", nil))
+	<-started
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/wildcat/api/counts", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("the request over the limit: wont 503 with Retry-After, got %d", rec.Code)
+	}
+	close(release)
+}
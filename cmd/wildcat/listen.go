@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// selfSignedValidity is the validity period of the self-signed certificate generated by --tls-self-signed.
+const selfSignedValidity = 365 * 24 * time.Hour
+
+// address returns the tcp address of the server, which is the bind address and the port.
+func (server *serverOptions) address() string {
+	return net.JoinHostPort(server.bind, strconv.Itoa(server.port))
+}
+
+func (server *serverOptions) isTLS() bool {
+	return server.tlsSelfSigned || server.tlsCert != ""
+}
+
+// listen creates the listener on the unix domain socket if --unix-socket is given, otherwise, on the tcp address.
+func (server *serverOptions) listen() (net.Listener, error) {
+	if server.unixSocket == "" {
+		return net.Listen("tcp", server.address())
+	}
+	if err := removeStaleSocket(server.unixSocket); err != nil {
+		return nil, err
+	}
+	return net.Listen("unix", server.unixSocket)
+}
+
+// removeStaleSocket removes the socket file left by the previous server, the socket in use is not removed.
+func removeStaleSocket(path string) error {
+	info, err := os.Stat(path)
+	if err != nil || info.Mode()&os.ModeSocket == 0 {
+		return nil
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s: the socket is already in use", path)
+	}
+	return os.Remove(path)
+}
+
+// tlsConfig returns the tls config of the server with the given certificate, or the generated self-signed certificate.
+// This method returns nil if the server serves over plain HTTP.
+func (server *serverOptions) tlsConfig() (*tls.Config, error) {
+	if !server.isTLS() {
+		return nil, nil
+	}
+	var certificate tls.Certificate
+	var err error
+	if server.tlsSelfSigned {
+		certificate, err = generateSelfSignedCertificate(server.certificateHosts())
+	} else {
+		certificate, err = tls.LoadX509KeyPair(server.tlsCert, server.tlsKey)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tls certificate: %w", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12}, nil
+}
+
+func (server *serverOptions) certificateHosts() []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	for _, host := range hosts {
+		if host == server.bind {
+			return hosts
+		}
+	}
+	if server.bind != "" {
+		hosts = append(hosts, server.bind)
+	}
+	return hosts
+}
+
+// generateSelfSignedCertificate generates the self-signed certificate for the given hosts, only for the local development.
+func generateSelfSignedCertificate(hosts []string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"wildcat self-signed"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
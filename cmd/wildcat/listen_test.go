@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// serveForTest serves the api by the given options, and returns the url and the client for it.
+func serveForTest(t *testing.T, opts *serverOptions) (string, *http.Client) {
+	listener, err := opts.listen()
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	httpServer := opts.newHTTPServer(createRestAPIServer(opts))
+	if httpServer.TLSConfig, err = opts.tlsConfig(); err != nil {
+		t.Fatalf("tls config: %v", err)
+	}
+	go serve(httpServer, listener)
+	t.Cleanup(func() { httpServer.Close() })
+	transport := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	url := "http://" + listener.Addr().String()
+	if opts.isTLS() {
+		url = "https://" + listener.Addr().String()
+	}
+	if opts.unixSocket != "" {
+		url = "http://unix"
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", opts.unixSocket)
+		}
+	}
+	return url, &http.Client{Transport: transport}
+}
+
+func TestListen(t *testing.T) {
+	testdata := []struct {
+		giveOpts *serverOptions
+		wontTLS  bool
+	}{
+		{&serverOptions{bind: "127.0.0.1"}, false},
+		{&serverOptions{bind: "127.0.0.1", tlsSelfSigned: true}, true},
+		{&serverOptions{unixSocket: filepath.Join(t.TempDir(), "wildcat.sock")}, false},
+	}
+	for _, td := range testdata {
+		url, client := serveForTest(t, td.giveOpts)
+		res, err := client.Post(url+"/wildcat/api/counts", "text/plain", strings.NewReader("hello world\n"))
+		if err != nil {
+			t.Errorf("%s: request failed: %v", td.giveOpts.listenURL(), err)
+			continue
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != 200 || !strings.Contains(string(body), `"lines":"1","words":"2"`) {
+			t.Errorf("%s: wont the results, got %d %s", td.giveOpts.listenURL(), res.StatusCode, string(body))
+		}
+		if (res.TLS != nil) != td.wontTLS {
+			t.Errorf("%s: tls did not match, wont %v", td.giveOpts.listenURL(), td.wontTLS)
+		}
+	}
+}
+
+func TestRemoveStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wildcat.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix domain socket is not available: %v", err)
+	}
+	if err := removeStaleSocket(path); err == nil {
+		t.Errorf("the socket in use should not be removed")
+	}
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+	if _, err := (&serverOptions{unixSocket: path}).listen(); err != nil {
+		t.Errorf("the stale socket should be removed, but got %v", err)
+	}
+}
+
+func TestSelfSignedCertificate(t *testing.T) {
+	opts := &serverOptions{bind: "192.0.2.1", tlsSelfSigned: true}
+	config, err := opts.tlsConfig()
+	if err != nil {
+		t.Fatalf("generating self-signed certificate: %v", err)
+	}
+	cert := config.Certificates[0]
+	if len(cert.Certificate) != 1 {
+		t.Fatalf("wont a certificate, got %d", len(cert.Certificate))
+	}
+	hosts := opts.certificateHosts()
+	if len(hosts) != 4 || hosts[3] != "192.0.2.1" {
+		t.Errorf("certificate hosts did not match, got %v", hosts)
+	}
+}
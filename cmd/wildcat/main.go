@@ -2,8 +2,11 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	flag "github.com/spf13/pflag"
 	"github.com/tamada/wildcat"
@@ -24,27 +27,107 @@ CLI_MODE_OPTIONS
     -w, --word                  Prints the number of words in each input file.
 
     -a, --all                   Reads the hidden files.
+        --depth <N>             Prints the results until the given depth, and the deeper results are
+                                rolled up into their ancestors. This option implies --subtotal.
+        --color <WHEN>          Colorizes the header, the total, and the errors in default format.
+                                Available values are: auto, always, and never. Default is auto.
+        --fail-fast             Cancels the counting on the first error.  This option is the same as --max-errors 1.
     -f, --format <FORMAT>       Prints results in a specified format.  Available formats are:
-                                csv, json, xml, and default. Default is default.
+                                csv, tsv, json, ndjson, xml, markdown, html, latex, template, tree,
+                                and default.
+                                Default is default.
+        --histogram <TYPE>      Prints the histogram of the given counter type with the statistics summary.
+                                Available types are: lines, words, chars, and bytes.
+                                The type must be counted. This option implies --stats.
     -H, --humanize              Prints sizes in humanization.
+                                Note that csv and tsv formats always print the raw numbers.
+        --max-errors <N>        Cancels the counting after the given number of errors.
+                                The results counted before cancelling are printed.  Default is 0 (no limit).
     -n, --no-ignore             Does not respect ignore files (.gitignore).
                                 If this option was specified, wildcat read .gitignore.
     -N, --no-extract-archive    Does not extract archive files. If this option was specified,
                                 wildcat treats archive files as the single binary file.
     -o, --output <DEST>         Specifies the destination of the result.  Default is standard output.
+        --no-header             Does not print the header row in csv and tsv formats.
+        --quote-all             Quotes all fields in csv and tsv formats.
     -P, --progress              Shows progress bar for counting.
     -S, --store-content         Sets to store the content of url targets.
-    -t, --with-threads <NUM>    Specifies the max thread number for counting. (Default is 10).
-                                The given value is less equals than 0, sets no max.
+        --stats                 Prints the statistics summary (min, max, mean, median, p90, and p99)
+                                of each counter type across the entries.
+        --subtotal              Prints the subtotals of directories, archives, and file lists.
+        --sort <KEY[:DIR]>      Sorts the results by the given key.  Available keys are:
+                                order, name, lines, words, chars, and bytes. Default is order.
+                                DIR is asc or desc.  The counts are sorted in descending order
+                                and the others are in ascending order by default.
+    -T, --template <TEMPLATE>   Specifies the template string or template file for template format.
+                                The template is Go text/template, and is executed for each entry.
+                                Available fields: .Name, .Order, .Index, .IsTotal, .EntryCount,
+                                .Lines, .Words, .Characters, and .Bytes.
+    -t, --with-threads <NUM>    Specifies the number of worker threads for counting. (Default is 10).
+                                The given value is less equals than 0, uses the number of CPUs.
+        --top <N>               Prints only the first N results after sorting.
+                                The total is still computed from all results.
     -@, --filelist              Treats the contents of arguments as file list.
 
     -h, --help                  Prints this message.
     -v, --version               Prints the version of wildcat.
 SERVER_MODE_OPTIONS
+        --allow-private-urls    Allows counting the urls on the loopback, private, and link-local addresses
+                                (e.g., the cloud metadata servers).  Default is not allowed.
+        --bind <ADDR>           Specifies the address to bind the server.  Default is all interfaces.
+        --cors-credentials      Allows the browsers to send the credentials in CORS requests.
+                                This option requires the explicit --cors-origins.
+        --cors-headers <HEADERS>
+                                Specifies the request headers allowed in CORS requests, separated by commas.
+                                Default is Accept,Authorization,Content-Type.
+        --cors-max-age <DURATION>
+                                Specifies the time to cache the results of the preflight requests.
+                                Default is 0 (no cache).
+        --cors-methods <METHODS>
+                                Specifies the methods allowed in CORS requests, separated by commas.
+                                Default is GET,POST,DELETE,OPTIONS.
+        --cors-origins <ORIGINS>
+                                Specifies the origins allowed in CORS requests, separated by commas.
+                                Default is * (any origins).  The empty string disallows CORS requests.
+        --job-timeout <DURATION>
+                                Specifies the timeout of each job of the jobs api. Default is 1h.
+                                0 means no timeout.
+        --job-ttl <DURATION>    Specifies the time to keep the finished jobs of the jobs api.
+                                Default is 10m (e.g., 30s, 10m, and 1h).
+        --max-body-size <SIZE>  Specifies the max size of the request bodies (e.g., 512K, 100M, and 1G).
+                                Default is 100M. 0 means no limit.
+        --max-expansion-size <SIZE>
+                                Specifies the max size expanded from each archive or compressed file.
+                                Default is 1G. 0 means no limit.
+        --max-jobs <NUM>        Specifies the max number of the running jobs of the jobs api.
+                                Default is 16. 0 means no limit.
+        --max-requests <NUM>    Specifies the max number of the concurrent requests. Default is 64.
+                                0 means no limit.
     -p, --port <PORT>           Specifies the port number of server.  Default is 8080.
                                 If '--server' option did not specified, wildcat ignores this option.
+        --rate-limit <NUM>      Specifies the max number of requests per minute for each api token.
+                                Default is 0 (no limit).
+        --request-timeout <DURATION>
+                                Specifies the timeout of each request. Default is 5m. 0 means no timeout.
+        --root <DIR>            Allows the json requests to count the files under the given directory.
+                                Default is not allowed (only urls are counted).
     -s, --server                Launches wildcat in the server mode. With this option, wildcat ignores
                                 CLI_MODE_OPTIONS and arguments.
+        --shutdown-timeout <DURATION>
+                                Specifies the time to wait for the requests in flight on SIGINT or SIGTERM.
+                                Default is 30s.
+        --store-dir <DIR>       Allows the store-content parameter of the requests, and stores the contents
+                                of url targets in the given directory.  Default is not allowed.
+        --token-file <FILE>     Requires the bearer tokens listed in the given file for the api.
+                                The file has a token per line in TOKEN[:RATE] format, RATE overrides
+                                --rate-limit.  The tokens are also given by WILDCAT_API_TOKENS
+                                environment variable, separated by commas.
+        --tls-cert <FILE>       Specifies the certificate file to serve over HTTPS.  Requires --tls-key.
+        --tls-key <FILE>        Specifies the private key file of the certificate given by --tls-cert.
+        --tls-self-signed       Serves over HTTPS with the self-signed certificate generated on startup.
+                                This option is for the local development.
+        --unix-socket <PATH>    Listens on the given unix domain socket instead of the tcp port.
+                                With this option, wildcat ignores --bind and --port.
 ARGUMENTS
     FILEs...                    Specifies counting targets. wildcat accepts zip/tar/tar.gz/tar.bz2/jar/war files.
     DIRs...                     Files in the given directory are as the input files.
@@ -62,6 +145,10 @@ type countingOptions struct {
 }
 
 func (co *countingOptions) generateCounter() wildcat.Counter {
+	return wildcat.NewCounter(co.counterType())
+}
+
+func (co *countingOptions) counterType() wildcat.CounterType {
 	var ct wildcat.CounterType = 0
 	if co.bytes {
 		ct = ct | wildcat.Bytes
@@ -78,18 +165,58 @@ func (co *countingOptions) generateCounter() wildcat.Counter {
 	if ct == 0 {
 		ct = wildcat.All
 	}
-	return wildcat.NewCounter(ct)
+	return ct
 }
 
 type printerOptions struct {
-	dest     string
-	format   string
-	humanize bool
+	dest      string
+	format    string
+	template  string
+	humanize  bool
+	noHeader  bool
+	quoteAll  bool
+	color     string
+	sort      string
+	top       int
+	subtotal  bool
+	depth     int
+	stats     bool
+	histogram string
 }
 
 type serverOptions struct {
 	server bool
+	bind   string
 	port   int
+	root   string
+	jobTTL time.Duration
+
+	storeDir         string
+	allowPrivateURLs bool
+
+	maxJobs    int
+	jobTimeout time.Duration
+
+	unixSocket    string
+	tlsCert       string
+	tlsKey        string
+	tlsSelfSigned bool
+
+	tokenFile string
+	rateLimit int
+	tokens    []*apiToken
+
+	corsOrigins     string
+	corsMethods     string
+	corsHeaders     string
+	corsCredentials bool
+	corsMaxAge      time.Duration
+
+	maxBodySize      byteSize
+	maxExpansionSize byteSize
+	maxRequests      int
+	requestTimeout   time.Duration
+	shutdownTimeout  time.Duration
 }
 
 func IsServerMode(so *serverOptions) bool {
@@ -126,14 +253,50 @@ func buildFlagSet(reads *wildcat.ReadOptions, runtime *wildcat.RuntimeOptions) (
 	flags.BoolVarP(&reads.AllFiles, "all", "a", false, "Reads the hidden files")
 	flags.BoolVarP(&opts.server.server, "server", "s", false, "Launches wildcat in the server mode")
 	flags.IntVarP(&opts.server.port, "port", "p", 8080, "Specifies the port number of server")
+	flags.StringVar(&opts.server.tokenFile, "token-file", "", "Requires the bearer tokens listed in the given file")
+	flags.IntVar(&opts.server.rateLimit, "rate-limit", 0, "Specifies the max number of requests per minute for each token")
+	flags.StringVar(&opts.server.corsOrigins, "cors-origins", defaultCORSOrigins, "Specifies the origins allowed in CORS requests")
+	flags.StringVar(&opts.server.corsMethods, "cors-methods", defaultCORSMethods, "Specifies the methods allowed in CORS requests")
+	flags.StringVar(&opts.server.corsHeaders, "cors-headers", defaultCORSHeaders, "Specifies the request headers allowed in CORS requests")
+	flags.BoolVar(&opts.server.corsCredentials, "cors-credentials", false, "Allows the credentials in CORS requests")
+	flags.DurationVar(&opts.server.corsMaxAge, "cors-max-age", 0, "Specifies the time to cache the preflight requests")
+	flags.StringVar(&opts.server.bind, "bind", "", "Specifies the address to bind the server")
+	flags.StringVar(&opts.server.unixSocket, "unix-socket", "", "Listens on the given unix domain socket")
+	flags.StringVar(&opts.server.tlsCert, "tls-cert", "", "Specifies the certificate file to serve over HTTPS")
+	flags.StringVar(&opts.server.tlsKey, "tls-key", "", "Specifies the private key file of the certificate")
+	flags.BoolVar(&opts.server.tlsSelfSigned, "tls-self-signed", false, "Serves over HTTPS with the self-signed certificate")
+	flags.DurationVar(&opts.server.jobTTL, "job-ttl", defaultJobTTL, "Specifies the time to keep the finished jobs")
+	flags.DurationVar(&opts.server.jobTimeout, "job-timeout", defaultJobTimeout, "Specifies the timeout of each job")
+	flags.IntVar(&opts.server.maxJobs, "max-jobs", defaultMaxJobs, "Specifies the max number of the running jobs")
+	opts.server.maxBodySize, opts.server.maxExpansionSize = defaultMaxBodySize, defaultMaxExpansionSize
+	flags.Var(&opts.server.maxBodySize, "max-body-size", "Specifies the max size of the request bodies")
+	flags.Var(&opts.server.maxExpansionSize, "max-expansion-size", "Specifies the max size expanded from each archive")
+	flags.IntVar(&opts.server.maxRequests, "max-requests", defaultMaxRequests, "Specifies the max number of the concurrent requests")
+	flags.DurationVar(&opts.server.requestTimeout, "request-timeout", defaultRequestTimeout, "Specifies the timeout of each request")
+	flags.DurationVar(&opts.server.shutdownTimeout, "shutdown-timeout", defaultShutdownTimeout, "Specifies the time to wait for the requests in flight")
+	flags.StringVar(&opts.server.root, "root", "", "Allows counting the files under the given directory in the server mode")
+	flags.StringVar(&opts.server.storeDir, "store-dir", "", "Allows store-content parameter, and stores the contents in the given directory")
+	flags.BoolVar(&opts.server.allowPrivateURLs, "allow-private-urls", false, "Allows counting the urls on the loopback, private, and link-local addresses")
 	flags.BoolVarP(&opts.help.help, "help", "h", false, "Prints this message")
 	flags.BoolVarP(&opts.help.version, "version", "v", false, "Prints the version of wildcat")
 	flags.StringVarP(&opts.printer.dest, "dest", "d", "", "Specifies the destination of the result")
 	flags.BoolVarP(&opts.printer.humanize, "humanize", "H", false, "Prints sizes in humanization")
+	flags.BoolVar(&opts.printer.noHeader, "no-header", false, "Does not print the header row in csv and tsv formats")
+	flags.BoolVar(&opts.printer.quoteAll, "quote-all", false, "Quotes all fields in csv and tsv formats")
+	flags.StringVar(&opts.printer.color, "color", "auto", "Specifies to colorize the results (auto, always, or never)")
+	flags.StringVar(&opts.printer.sort, "sort", "", "Specifies the sort key of the results")
+	flags.IntVar(&opts.printer.top, "top", 0, "Prints only the first N results")
+	flags.BoolVar(&opts.printer.subtotal, "subtotal", false, "Prints the subtotals of directories, archives, and file lists")
+	flags.IntVar(&opts.printer.depth, "depth", 0, "Specifies the max depth of printed results")
+	flags.BoolVar(&opts.printer.stats, "stats", false, "Prints the statistics summary of the results")
+	flags.StringVar(&opts.printer.histogram, "histogram", "", "Prints the histogram of the given counter type")
 	flags.BoolVarP(&runtime.ShowProgress, "show-progress", "P", false, "Shows progress")
 	flags.BoolVarP(&runtime.StoreContent, "store-content", "S", false, "Sets to store the content of url targets")
-	flags.Int64VarP(&runtime.ThreadNumber, "with-threads", "t", 10, "Specifies the max thread number")
+	flags.BoolVar(&runtime.FailFast, "fail-fast", false, "Cancels the counting on the first error")
+	flags.IntVar(&runtime.MaxErrors, "max-errors", 0, "Cancels the counting after the given number of errors")
+	flags.Int64VarP(&runtime.ThreadNumber, "with-threads", "t", 10, "Specifies the number of worker threads")
 	flags.StringVarP(&opts.printer.format, "format", "f", "default", "Specifies the resultant format")
+	flags.StringVarP(&opts.printer.template, "template", "T", "", "Specifies the template for template format")
 	return flags, opts
 }
 
@@ -142,7 +305,7 @@ func parseOptions(args []string, reads *wildcat.ReadOptions, runtime *wildcat.Ru
 	if err := flags.Parse(args); err != nil {
 		return nil, nil, err
 	}
-	if err := validateOptions(opts); err != nil {
+	if err := validateOptions(opts, runtime); err != nil {
 		return nil, nil, err
 	}
 	return wildcat.NewArgf(flags.Args()[1:], reads, runtime), opts, nil
@@ -158,29 +321,125 @@ func printAll(printerOpts *printerOptions, rs *wildcat.ResultSet) error {
 		dest = file
 		defer file.Close()
 	}
-	printer := wildcat.NewPrinter(dest, printerOpts.format, wildcat.BuildSizer(printerOpts.humanize))
+	printer, err := printerOpts.createPrinter(dest, printerOpts.isColorEnabled(dest))
+	if err != nil {
+		return err
+	}
+	if _, ok := printer.(wildcat.ErrorPrinter); !ok && rs.Size() == 0 && len(rs.Errors()) > 0 {
+		return nil
+	}
+	if err := printerOpts.applyTo(rs); err != nil {
+		return err
+	}
 	return rs.Print(printer)
 }
 
-func performImpl(argf *wildcat.Argf, opts *options) *errors.Center {
+// applyTo sets the sort order, the rollup, and the statistics of the given ResultSet by the receiver options.
+func (po *printerOptions) applyTo(rs *wildcat.ResultSet) error {
+	order, err := wildcat.ParseSortOrder(po.sort, po.top)
+	if err != nil {
+		return err
+	}
+	rs.SetSortOrder(order)
+	rs.SetRollup(&wildcat.Rollup{Enabled: po.subtotal || po.depth > 0, Depth: po.depth})
+	stats, err := po.statisticsOptions()
+	if err != nil {
+		return err
+	}
+	rs.SetStatistics(stats)
+	return nil
+}
+
+func isTerminal(file *os.File) bool {
+	stat, err := file.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// isColorEnabled decides to decorate the results or not by --color option and the given destination.
+// In auto mode, the results are colored only if the destination is the terminal and NO_COLOR is not set.
+func (po *printerOptions) isColorEnabled(dest *os.File) bool {
+	switch strings.ToLower(po.color) {
+	case "always":
+		return true
+	case "never":
+		return false
+	default:
+		return isTerminal(dest) && os.Getenv("NO_COLOR") == ""
+	}
+}
+
+// statisticsOptions builds the settings of the statistics summary, --histogram option implies --stats.
+func (po *printerOptions) statisticsOptions() (*wildcat.StatisticsOptions, error) {
+	if po.histogram == "" {
+		return &wildcat.StatisticsOptions{Enabled: po.stats}, nil
+	}
+	ct, err := wildcat.ParseCounterType(po.histogram)
+	if err != nil {
+		return nil, err
+	}
+	return &wildcat.StatisticsOptions{Enabled: true, HistogramType: ct}, nil
+}
+
+func (po *printerOptions) createPrinter(dest io.Writer, color bool) (wildcat.Printer, error) {
+	switch strings.ToLower(po.format) {
+	case "template":
+		text, err := wildcat.ReadTemplate(po.template)
+		if err != nil {
+			return nil, err
+		}
+		return wildcat.NewTemplatePrinter(dest, text)
+	case "csv":
+		return wildcat.NewCsvPrinter(dest, &wildcat.CsvOptions{Delimiter: ',', NoHeader: po.noHeader, QuoteAll: po.quoteAll}), nil
+	case "tsv":
+		return wildcat.NewCsvPrinter(dest, &wildcat.CsvOptions{Delimiter: '\t', NoHeader: po.noHeader, QuoteAll: po.quoteAll}), nil
+	case "default":
+		return wildcat.NewDefaultPrinter(dest, wildcat.BuildSizer(po.humanize), color), nil
+	default:
+		return wildcat.NewPrinter(dest, po.format, wildcat.BuildSizer(po.humanize)), nil
+	}
+}
+
+const (
+	exitSuccess        = 0
+	exitFailure        = 1
+	exitPartialFailure = 2
+)
+
+func performImpl(argf *wildcat.Argf, opts *options) (*wildcat.ResultSet, *errors.Center, error) {
 	wildcat := wildcat.NewWildcat(argf.Options, argf.RuntimeOpts, func() wildcat.Counter {
 		return opts.count.generateCounter()
 	})
 	rs, ec := wildcat.CountAll(argf)
-	if !ec.IsEmpty() {
-		return ec
+	return rs, ec, printAll(opts.printer, rs)
+}
+
+// exitStatus returns the exit status by the results and the errors in counting.
+// If some entries failed and the others succeeded, this function returns exitPartialFailure.
+func exitStatus(rs *wildcat.ResultSet, ec *errors.Center) int {
+	switch {
+	case ec.IsEmpty():
+		return exitSuccess
+	case rs.Size() == 0:
+		return exitFailure
+	default:
+		return exitPartialFailure
 	}
-	ec.Push(printAll(opts.printer, rs))
-	return ec
 }
 
 func perform(argf *wildcat.Argf, opts *options) int {
-	err := performImpl(argf, opts)
-	if err != nil && !err.IsEmpty() {
-		fmt.Println(err.Error())
-		return 1
+	rs, ec, err := performImpl(argf, opts)
+	color := opts.printer.isColorEnabled(os.Stderr)
+	if !ec.IsEmpty() {
+		fmt.Fprintln(os.Stderr, wildcat.ColoredError(ec.Error(), color))
+	}
+	if ec.IsExceeded() {
+		fmt.Fprintln(os.Stderr, wildcat.ColoredError(fmt.Sprintf("counting was cancelled by %d error(s)", ec.Size()), color))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, wildcat.ColoredError(err.Error(), color))
+		return exitFailure
 	}
-	return 0
+	return exitStatus(rs, ec)
 }
 
 func printHelp(opts *helpOptions, prog string) int {
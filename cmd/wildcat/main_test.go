@@ -52,19 +52,19 @@ func Example_wildcat2() {
 
 	goMain([]string{"wildcat", "-@", "-f", "csv", "-b", "-w", "--character"})
 	// Output:
-	// file name,words,characters,bytes
-	// ../../testdata/wc/humpty_dumpty.txt,"26","142","142"
-	// ../../testdata/wc/ja/sakura_sakura.txt,"26","118","298"
-	// total,"52","260","440"
+	// type,name,words,characters,bytes
+	// entry,../../testdata/wc/humpty_dumpty.txt,26,142,142
+	// entry,../../testdata/wc/ja/sakura_sakura.txt,26,118,298
+	// total,total,52,260,440
 }
 
 func Example_wildcat() {
 	goMain([]string{"wildcat", "../../testdata/wc/humpty_dumpty.txt", "../../testdata/wc/ja/sakura_sakura.txt", "-l", "-b", "-c", "-w"})
 	// Output:
-	//       lines      words characters      bytes
-	//           4         26        142        142 ../../testdata/wc/humpty_dumpty.txt
-	//          15         26        118        298 ../../testdata/wc/ja/sakura_sakura.txt
-	//          19         52        260        440 total (2 entries)
+	//  lines words characters bytes
+	//      4    26        142   142 ../../testdata/wc/humpty_dumpty.txt
+	//     15    26        118   298 ../../testdata/wc/ja/sakura_sakura.txt
+	//     19    52        260   440 total (2 entries)
 }
 
 func Example_help() {
@@ -81,27 +81,107 @@ func Example_help() {
 	//     -w, --word                  Prints the number of words in each input file.
 	//
 	//     -a, --all                   Reads the hidden files.
+	//         --depth <N>             Prints the results until the given depth, and the deeper results are
+	//                                 rolled up into their ancestors. This option implies --subtotal.
+	//         --color <WHEN>          Colorizes the header, the total, and the errors in default format.
+	//                                 Available values are: auto, always, and never. Default is auto.
+	//         --fail-fast             Cancels the counting on the first error.  This option is the same as --max-errors 1.
 	//     -f, --format <FORMAT>       Prints results in a specified format.  Available formats are:
-	//                                 csv, json, xml, and default. Default is default.
+	//                                 csv, tsv, json, ndjson, xml, markdown, html, latex, template, tree,
+	//                                 and default.
+	//                                 Default is default.
+	//         --histogram <TYPE>      Prints the histogram of the given counter type with the statistics summary.
+	//                                 Available types are: lines, words, chars, and bytes.
+	//                                 The type must be counted. This option implies --stats.
 	//     -H, --humanize              Prints sizes in humanization.
+	//                                 Note that csv and tsv formats always print the raw numbers.
+	//         --max-errors <N>        Cancels the counting after the given number of errors.
+	//                                 The results counted before cancelling are printed.  Default is 0 (no limit).
 	//     -n, --no-ignore             Does not respect ignore files (.gitignore).
 	//                                 If this option was specified, wildcat read .gitignore.
 	//     -N, --no-extract-archive    Does not extract archive files. If this option was specified,
 	//                                 wildcat treats archive files as the single binary file.
 	//     -o, --output <DEST>         Specifies the destination of the result.  Default is standard output.
+	//         --no-header             Does not print the header row in csv and tsv formats.
+	//         --quote-all             Quotes all fields in csv and tsv formats.
 	//     -P, --progress              Shows progress bar for counting.
 	//     -S, --store-content         Sets to store the content of url targets.
-	//     -t, --with-threads <NUM>    Specifies the max thread number for counting. (Default is 10).
-	//                                 The given value is less equals than 0, sets no max.
+	//         --stats                 Prints the statistics summary (min, max, mean, median, p90, and p99)
+	//                                 of each counter type across the entries.
+	//         --subtotal              Prints the subtotals of directories, archives, and file lists.
+	//         --sort <KEY[:DIR]>      Sorts the results by the given key.  Available keys are:
+	//                                 order, name, lines, words, chars, and bytes. Default is order.
+	//                                 DIR is asc or desc.  The counts are sorted in descending order
+	//                                 and the others are in ascending order by default.
+	//     -T, --template <TEMPLATE>   Specifies the template string or template file for template format.
+	//                                 The template is Go text/template, and is executed for each entry.
+	//                                 Available fields: .Name, .Order, .Index, .IsTotal, .EntryCount,
+	//                                 .Lines, .Words, .Characters, and .Bytes.
+	//     -t, --with-threads <NUM>    Specifies the number of worker threads for counting. (Default is 10).
+	//                                 The given value is less equals than 0, uses the number of CPUs.
+	//         --top <N>               Prints only the first N results after sorting.
+	//                                 The total is still computed from all results.
 	//     -@, --filelist              Treats the contents of arguments as file list.
 	//
 	//     -h, --help                  Prints this message.
 	//     -v, --version               Prints the version of wildcat.
 	// SERVER_MODE_OPTIONS
+	//         --allow-private-urls    Allows counting the urls on the loopback, private, and link-local addresses
+	//                                 (e.g., the cloud metadata servers).  Default is not allowed.
+	//         --bind <ADDR>           Specifies the address to bind the server.  Default is all interfaces.
+	//         --cors-credentials      Allows the browsers to send the credentials in CORS requests.
+	//                                 This option requires the explicit --cors-origins.
+	//         --cors-headers <HEADERS>
+	//                                 Specifies the request headers allowed in CORS requests, separated by commas.
+	//                                 Default is Accept,Authorization,Content-Type.
+	//         --cors-max-age <DURATION>
+	//                                 Specifies the time to cache the results of the preflight requests.
+	//                                 Default is 0 (no cache).
+	//         --cors-methods <METHODS>
+	//                                 Specifies the methods allowed in CORS requests, separated by commas.
+	//                                 Default is GET,POST,DELETE,OPTIONS.
+	//         --cors-origins <ORIGINS>
+	//                                 Specifies the origins allowed in CORS requests, separated by commas.
+	//                                 Default is * (any origins).  The empty string disallows CORS requests.
+	//         --job-timeout <DURATION>
+	//                                 Specifies the timeout of each job of the jobs api. Default is 1h.
+	//                                 0 means no timeout.
+	//         --job-ttl <DURATION>    Specifies the time to keep the finished jobs of the jobs api.
+	//                                 Default is 10m (e.g., 30s, 10m, and 1h).
+	//         --max-body-size <SIZE>  Specifies the max size of the request bodies (e.g., 512K, 100M, and 1G).
+	//                                 Default is 100M. 0 means no limit.
+	//         --max-expansion-size <SIZE>
+	//                                 Specifies the max size expanded from each archive or compressed file.
+	//                                 Default is 1G. 0 means no limit.
+	//         --max-jobs <NUM>        Specifies the max number of the running jobs of the jobs api.
+	//                                 Default is 16. 0 means no limit.
+	//         --max-requests <NUM>    Specifies the max number of the concurrent requests. Default is 64.
+	//                                 0 means no limit.
 	//     -p, --port <PORT>           Specifies the port number of server.  Default is 8080.
 	//                                 If '--server' option did not specified, wildcat ignores this option.
+	//         --rate-limit <NUM>      Specifies the max number of requests per minute for each api token.
+	//                                 Default is 0 (no limit).
+	//         --request-timeout <DURATION>
+	//                                 Specifies the timeout of each request. Default is 5m. 0 means no timeout.
+	//         --root <DIR>            Allows the json requests to count the files under the given directory.
+	//                                 Default is not allowed (only urls are counted).
 	//     -s, --server                Launches wildcat in the server mode. With this option, wildcat ignores
 	//                                 CLI_MODE_OPTIONS and arguments.
+	//         --shutdown-timeout <DURATION>
+	//                                 Specifies the time to wait for the requests in flight on SIGINT or SIGTERM.
+	//                                 Default is 30s.
+	//         --store-dir <DIR>       Allows the store-content parameter of the requests, and stores the contents
+	//                                 of url targets in the given directory.  Default is not allowed.
+	//         --token-file <FILE>     Requires the bearer tokens listed in the given file for the api.
+	//                                 The file has a token per line in TOKEN[:RATE] format, RATE overrides
+	//                                 --rate-limit.  The tokens are also given by WILDCAT_API_TOKENS
+	//                                 environment variable, separated by commas.
+	//         --tls-cert <FILE>       Specifies the certificate file to serve over HTTPS.  Requires --tls-key.
+	//         --tls-key <FILE>        Specifies the private key file of the certificate given by --tls-cert.
+	//         --tls-self-signed       Serves over HTTPS with the self-signed certificate generated on startup.
+	//                                 This option is for the local development.
+	//         --unix-socket <PATH>    Listens on the given unix domain socket instead of the tcp port.
+	//                                 With this option, wildcat ignores --bind and --port.
 	// ARGUMENTS
 	//     FILEs...                    Specifies counting targets. wildcat accepts zip/tar/tar.gz/tar.bz2/jar/war files.
 	//     DIRs...                     Files in the given directory are as the input files.
@@ -124,6 +204,36 @@ func TestParseOptions(t *testing.T) {
 		{[]string{"-h"}, true, []string{}, "default", false},
 		{[]string{"-f", "csv"}, false, []string{}, "csv", false},
 		{[]string{"--format", "xml"}, false, []string{}, "xml", false},
+		{[]string{"--format", "template"}, false, []string{}, "template", true},
+		{[]string{"--color", "sometimes"}, false, []string{}, "default", true},
+		{[]string{"--color", "never"}, false, []string{}, "default", false},
+		{[]string{"--sort", "bytes:desc", "--top", "10"}, false, []string{}, "default", false},
+		{[]string{"--sort", "size"}, false, []string{}, "default", true},
+		{[]string{"--top", "-1"}, false, []string{}, "default", true},
+		{[]string{"--format", "template", "--template", "{{.Name}}"}, false, []string{}, "template", false},
+		{[]string{"--format", "template", "--template", "{{.Name"}, false, []string{}, "template", true},
+		{[]string{"--format", "template", "--template", "{{.Name}}", "--stats"}, false, []string{}, "template", true},
+		{[]string{"--format", "template", "--template", "{{.Name}}", "--histogram", "lines"}, false, []string{}, "template", true},
+		{[]string{"--format", "template", "--template", "{{.Name | unknown}}"}, false, []string{}, "template", true},
+		{[]string{"--stats", "--histogram", "bytes"}, false, []string{}, "default", false},
+		{[]string{"--histogram", "size"}, false, []string{}, "default", true},
+		{[]string{"--line", "--histogram", "bytes"}, false, []string{}, "default", true},
+		{[]string{"--fail-fast", "--max-errors", "3"}, false, []string{}, "default", false},
+		{[]string{"--max-errors", "-1"}, false, []string{}, "default", true},
+		{[]string{"--server", "--root", "../../testdata"}, false, []string{}, "default", false},
+		{[]string{"--server", "--root", "not_exist_dir"}, false, []string{}, "default", true},
+		{[]string{"--server", "--job-ttl", "0s"}, false, []string{}, "default", true},
+		{[]string{"--server", "--max-body-size", "10M", "--max-expansion-size", "1G"}, false, []string{}, "default", false},
+		{[]string{"--server", "--max-body-size", "10X"}, true, []string{}, "default", true},
+		{[]string{"--server", "--max-requests", "-1"}, false, []string{}, "default", true},
+		{[]string{"--server", "--max-jobs", "-1"}, false, []string{}, "default", true},
+		{[]string{"--server", "--job-timeout", "-1s"}, false, []string{}, "default", true},
+		{[]string{"--server", "--tls-self-signed", "--bind", "127.0.0.1"}, false, []string{}, "default", false},
+		{[]string{"--server", "--cors-credentials"}, false, []string{}, "default", true},
+		{[]string{"--server", "--cors-credentials", "--cors-origins", "https://example.com"}, false, []string{}, "default", false},
+		{[]string{"--server", "--tls-cert", "../../go.mod"}, false, []string{}, "default", true},
+		{[]string{"--server", "--tls-cert", "not_exist.pem", "--tls-key", "not_exist.key"}, false, []string{}, "default", true},
+		{[]string{"--server", "--tls-self-signed", "--tls-cert", "../../go.mod", "--tls-key", "../../go.sum"}, false, []string{}, "default", true},
 		{[]string{"../../testdata/"}, false, []string{"../../testdata"}, "default", false},
 	}
 	for _, td := range testdata {
@@ -146,3 +256,21 @@ func TestParseOptions(t *testing.T) {
 		}
 	}
 }
+
+func TestExitStatus(t *testing.T) {
+	testdata := []struct {
+		giveArgs   []string
+		wontStatus int
+	}{
+		{[]string{"wildcat", "../../testdata/wc/humpty_dumpty.txt", "-d", "result.txt"}, 0},
+		{[]string{"wildcat", "../../testdata/wc/humpty_dumpty.txt", "not_exist.txt", "-d", "result.txt"}, 2},
+		{[]string{"wildcat", "not_exist.txt", "-d", "result.txt"}, 1},
+		{[]string{"wildcat", "../../testdata/wc/humpty_dumpty.txt", "-f", "template", "-T", "{{.Name", "-d", "result.txt"}, 1},
+	}
+	defer os.Remove("result.txt")
+	for _, td := range testdata {
+		if got := goMain(td.giveArgs); got != td.wontStatus {
+			t.Errorf("goMain(%v) exit status did not match, wont %d, got %d", td.giveArgs, td.wontStatus, got)
+		}
+	}
+}
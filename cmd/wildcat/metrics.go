@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tamada/wildcat"
+)
+
+// latencyBuckets are the upper bounds (in seconds) of the histogram buckets of the request latencies.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// requestKey is the labels of the request metrics.
+type requestKey struct {
+	method string
+	route  string
+	status int
+}
+
+func (key requestKey) labels() string {
+	return fmt.Sprintf(`method="%s",route="%s",status="%d"`, key.method, key.route, key.status)
+}
+
+// latencyHistogram is the cumulative histogram of the request latencies.
+type latencyHistogram struct {
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+func (h *latencyHistogram) observe(seconds float64) {
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// serverMetrics is the metrics of the server, which are exposed in the Prometheus text format by /metrics.
+type serverMetrics struct {
+	mutex    sync.Mutex
+	requests map[requestKey]*latencyHistogram
+	inFlight int64
+	bytes    int64
+	files    int64
+	members  int64
+}
+
+func newServerMetrics() *serverMetrics {
+	return &serverMetrics{requests: map[requestKey]*latencyHistogram{}}
+}
+
+// statusRecorder records the status code of the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+// middleware records the number and the latency of the requests by the method, the route, and the status code.
+func (metrics *serverMetrics) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		atomic.AddInt64(&metrics.inFlight, 1)
+		defer atomic.AddInt64(&metrics.inFlight, -1)
+		recorder := &statusRecorder{ResponseWriter: res, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(recorder, req)
+		route := req.URL.Path
+		if current := mux.CurrentRoute(req); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+		metrics.observe(requestKey{method: req.Method, route: route, status: recorder.status}, time.Since(start))
+	})
+}
+
+func (metrics *serverMetrics) observe(key requestKey, latency time.Duration) {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	histogram, ok := metrics.requests[key]
+	if !ok {
+		histogram = &latencyHistogram{buckets: make([]uint64, len(latencyBuckets))}
+		metrics.requests[key] = histogram
+	}
+	histogram.observe(latency.Seconds())
+}
+
+// observeResults records the counted files, bytes, and archive members of the given results.
+// The bytes are recorded only if the results count the bytes.
+func (metrics *serverMetrics) observeResults(rs *wildcat.ResultSet) {
+	if rs == nil {
+		return
+	}
+	atomic.AddInt64(&metrics.files, int64(rs.Size()))
+	atomic.AddInt64(&metrics.bytes, rs.TotalCount(wildcat.Bytes))
+	atomic.AddInt64(&metrics.members, int64(rs.ArchivedSize()))
+}
+
+func (metrics *serverMetrics) sortedKeys() []requestKey {
+	keys := []requestKey{}
+	for key := range metrics.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].labels() < keys[j].labels()
+	})
+	return keys
+}
+
+func writeMetricHeader(out io.Writer, name, metricType, help string) {
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func (metrics *serverMetrics) writeRequests(out io.Writer) {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	keys := metrics.sortedKeys()
+	writeMetricHeader(out, "wildcat_http_requests_total", "counter", "The number of the api requests by the method, the route, and the status code.")
+	for _, key := range keys {
+		fmt.Fprintf(out, "wildcat_http_requests_total{%s} %d\n", key.labels(), metrics.requests[key].count)
+	}
+	writeMetricHeader(out, "wildcat_http_request_duration_seconds", "histogram", "The latencies of the api requests by the method, the route, and the status code.")
+	for _, key := range keys {
+		histogram := metrics.requests[key]
+		for i, bound := range latencyBuckets {
+			fmt.Fprintf(out, "wildcat_http_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n", key.labels(), formatFloat(bound), histogram.buckets[i])
+		}
+		fmt.Fprintf(out, "wildcat_http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", key.labels(), histogram.count)
+		fmt.Fprintf(out, "wildcat_http_request_duration_seconds_sum{%s} %s\n", key.labels(), formatFloat(histogram.sum))
+		fmt.Fprintf(out, "wildcat_http_request_duration_seconds_count{%s} %d\n", key.labels(), histogram.count)
+	}
+}
+
+// write writes the metrics in the Prometheus text format.
+func (metrics *serverMetrics) write(out io.Writer, jobs int) {
+	metrics.writeRequests(out)
+	values := []struct {
+		name       string
+		metricType string
+		help       string
+		value      int64
+	}{
+		{"wildcat_http_requests_in_flight", "gauge", "The number of the api requests in flight.", atomic.LoadInt64(&metrics.inFlight)},
+		{"wildcat_counted_files_total", "counter", "The number of the counted files.", atomic.LoadInt64(&metrics.files)},
+		{"wildcat_counted_bytes_total", "counter", "The number of the counted bytes.", atomic.LoadInt64(&metrics.bytes)},
+		{"wildcat_archive_members_total", "counter", "The number of the files extracted from the archives.", atomic.LoadInt64(&metrics.members)},
+		{"wildcat_jobs_in_flight", "gauge", "The number of the running jobs.", int64(jobs)},
+	}
+	for _, v := range values {
+		writeMetricHeader(out, v.name, v.metricType, v.help)
+		fmt.Fprintf(out, "%s %d\n", v.name, v.value)
+	}
+}
+
+func (server *restServer) metricsHandler(res http.ResponseWriter, req *http.Request) {
+	res.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	server.metrics.write(res, server.jobs.running())
+}
+
+// healthHandler responds 200 while the server process is alive.
+func (server *restServer) healthHandler(res http.ResponseWriter, req *http.Request) {
+	respondJSON(res, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// readyHandler responds 200 if the server accepts the requests, and 503 while shutting down, or the requests reach --max-requests.
+func (server *restServer) readyHandler(res http.ResponseWriter, req *http.Request) {
+	switch {
+	case atomic.LoadInt32(&server.shuttingDown) != 0:
+		respondJSON(res, http.StatusServiceUnavailable, map[string]string{"status": "shutting down"})
+	case server.requests != nil && len(server.requests) == cap(server.requests):
+		respondJSON(res, http.StatusServiceUnavailable, map[string]string{"status": "busy"})
+	default:
+		respondJSON(res, http.StatusOK, map[string]string{"status": "ready"})
+	}
+}
+
+// shutdown marks the server as not ready, this method is called on the graceful shutdown.
+func (server *restServer) shutdown() {
+	atomic.StoreInt32(&server.shuttingDown, 1)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	server := newRestServer(&serverOptions{maxRequests: 1})
+	router := server.router()
+	testdata := []struct {
+		giveURL    string
+		giveBefore func()
+		wontStatus int
+		wontBody   string
+	}{
+		{"/healthz", func() {}, 200, `{"status":"ok"}`},
+		{"/readyz", func() {}, 200, `{"status":"ready"}`},
+		{"/readyz", func() { server.acquire() }, 503, `{"status":"busy"}`},
+		{"/readyz", func() { server.release(); server.shutdown() }, 503, `{"status":"shutting down"}`},
+		{"/healthz", func() {}, 200, `{"status":"ok"}`},
+	}
+	for _, td := range testdata {
+		td.giveBefore()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", td.giveURL, nil))
+		if rec.Code != td.wontStatus || rec.Body.String() != td.wontBody {
+			t.Errorf("%s: wont %d %s, got %d %s", td.giveURL, td.wontStatus, td.wontBody, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+// readinessListener records the readiness of the server on closing the listener.
+type readinessListener struct {
+	net.Listener
+	router http.Handler
+	status int
+}
+
+func (rl *readinessListener) Close() error {
+	rec := httptest.NewRecorder()
+	rl.router.ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+	rl.status = rec.Code
+	return rl.Listener.Close()
+}
+
+func TestNotReadyBeforeShutdown(t *testing.T) {
+	opts := &serverOptions{bind: "127.0.0.1", shutdownTimeout: time.Second}
+	rest := newRestServer(opts)
+	listener, err := opts.listen()
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	rl := &readinessListener{Listener: listener, router: rest.router()}
+	httpServer := opts.newHTTPServer(rl.router)
+	served := make(chan error, 1)
+	go func() { served <- serve(httpServer, rl) }()
+	if _, err := http.Get("http://" + listener.Addr().String() + "/readyz"); err != nil {
+		t.Fatalf("readyz: %v", err)
+	}
+	opts.shutdown(rest, httpServer)
+	if err := <-served; err != http.ErrServerClosed {
+		t.Errorf("serve: wont %v, got %v", http.ErrServerClosed, err)
+	}
+	if rl.status != http.StatusServiceUnavailable {
+		t.Errorf("readyz on closing the listener: wont 503, got %d", rl.status)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	router := createRestAPIServer(&serverOptions{tokens: []*apiToken{{"secret", 0}}})
+	jar, _ := ioutil.ReadFile("../../testdata/archives/wc.jar")
+	requests := []struct {
+		giveURL   string
+		giveToken string
+		giveBody  []byte
+	}{
+		{"/wildcat/api/counts", "secret", []byte("hello world\n")},
+		{"/wildcat/api/counts", "secret", jar},
+		{"/wildcat/api/counts", "", []byte("hello world\n")},
+	}
+	for _, r := range requests {
+		req := httptest.NewRequest("POST", r.giveURL, bytes.NewReader(r.giveBody))
+		if r.giveToken != "" {
+			req.Header.Set("Authorization", "Bearer "+r.giveToken)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	wonts := []string{
+		`wildcat_http_requests_total{method="POST",route="/wildcat/api/counts",status="200"} 2`,
+		`wildcat_http_requests_total{method="POST",route="/wildcat/api/counts",status="401"} 1`,
+		`wildcat_http_request_duration_seconds_bucket{method="POST",route="/wildcat/api/counts",status="200",le="+Inf"} 2`,
+		`wildcat_http_request_duration_seconds_count{method="POST",route="/wildcat/api/counts",status="401"} 1`,
+		"# TYPE wildcat_http_request_duration_seconds histogram\n",
+		"wildcat_http_requests_in_flight 0\n",
+		"wildcat_counted_files_total 5\n",
+		"wildcat_counted_bytes_total 1793\n",
+		"wildcat_archive_members_total 4\n",
+		"wildcat_jobs_in_flight 0\n",
+	}
+	for _, wont := range wonts {
+		if !strings.Contains(body, wont) {
+			t.Errorf("metrics did not contain %s,\ngot %s", wont, body)
+		}
+	}
+	if contentType := rec.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("content type did not match, got %s", contentType)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// responseFormat shows the format of the response body, and the media types of it.
+// The first media type is used for the Content-Type of the response.
+type responseFormat struct {
+	name       string
+	mediaTypes []string
+}
+
+func (rf *responseFormat) contentType() string {
+	return rf.mediaTypes[0] + "; charset=utf-8"
+}
+
+var responseFormats = []*responseFormat{
+	{"json", []string{"application/json", "text/json"}},
+	{"ndjson", []string{"application/x-ndjson", "application/ndjson"}},
+	{"xml", []string{"application/xml", "text/xml"}},
+	{"default", []string{"text/plain"}},
+	{"csv", []string{"text/csv"}},
+}
+
+var jsonFormat = responseFormats[0]
+
+// statusError is the error with the status code of the response.
+type statusError struct {
+	status int
+	err    error
+}
+
+func (se *statusError) Error() string {
+	return se.err.Error()
+}
+
+func (se *statusError) Unwrap() error {
+	return se.err
+}
+
+// negotiateFormat finds the format of the response by the format query parameter, or the Accept header.
+// The query parameter takes precedence over the Accept header, and json is the default format.
+// If the Accept header has no available media types, the returned error is statusError with 406 Not Acceptable.
+func negotiateFormat(req *http.Request) (*responseFormat, error) {
+	if name := req.URL.Query().Get("format"); name != "" {
+		for _, format := range responseFormats {
+			if strings.EqualFold(format.name, name) {
+				return format, nil
+			}
+		}
+		return nil, fmt.Errorf("%s: unknown format", name)
+	}
+	accept := req.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return jsonFormat, nil
+	}
+	for _, mediaRange := range parseAccept(accept) {
+		if format := findFormat(mediaRange); format != nil {
+			return format, nil
+		}
+	}
+	return nil, &statusError{status: http.StatusNotAcceptable, err: fmt.Errorf("%s: not acceptable media types", accept)}
+}
+
+// parseAccept parses the given Accept header, and returns the media ranges in the descending order of their quality values.
+// The media ranges with q=0 are excluded.
+func parseAccept(accept string) []string {
+	type mediaRange struct {
+		name    string
+		quality float64
+	}
+	ranges := []*mediaRange{}
+	for _, item := range strings.Split(accept, ",") {
+		params := strings.Split(item, ";")
+		mr := &mediaRange{name: strings.ToLower(strings.TrimSpace(params[0])), quality: 1.0}
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if value, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					mr.quality = value
+				}
+			}
+		}
+		if mr.name != "" && mr.quality > 0 {
+			ranges = append(ranges, mr)
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].quality > ranges[j].quality })
+	results := []string{}
+	for _, mr := range ranges {
+		results = append(results, mr.name)
+	}
+	return results
+}
+
+// findFormat returns the format matched with the given media range (e.g., text/csv, text/*, and */*), or nil if not found.
+// The media ranges with the wildcard are matched with only the first media types of the formats.
+func findFormat(mediaRange string) *responseFormat {
+	if mediaRange == "*/*" {
+		return jsonFormat
+	}
+	for _, format := range responseFormats {
+		if strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(format.mediaTypes[0], strings.TrimSuffix(mediaRange, "*")) {
+			return format
+		}
+		for _, mediaType := range format.mediaTypes {
+			if mediaType == mediaRange {
+				return format
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/tamada/wildcat"
+)
+
+// maxRequestThreads is the max number of worker threads which clients can request by threads parameter.
+const maxRequestThreads = 64
+
+// requestOptions is the options of counting and printing given by the query parameters of the request.
+// The options have the same capabilities as the options of CLI mode.
+type requestOptions struct {
+	reads    *wildcat.ReadOptions
+	runtime  *wildcat.RuntimeOptions
+	count    *countingOptions
+	printer  *printerOptions
+	format   *responseFormat
+	fileName string
+}
+
+func (ro *requestOptions) newWildcat() *wildcat.Wildcat {
+	return wildcat.NewWildcat(ro.reads, ro.runtime, ro.count.generateCounter)
+}
+
+func (ro *requestOptions) sizer() wildcat.Sizer {
+	return wildcat.BuildSizer(ro.printer.humanize)
+}
+
+// parameterError shows the invalid value of the query parameter.
+type parameterError struct {
+	Parameter string `json:"parameter"`
+	Value     string `json:"value"`
+	Message   string `json:"message"`
+}
+
+// parameterErrors is the errors of the query parameters, which are responded as 400 Bad Request.
+type parameterErrors []*parameterError
+
+func (pe parameterErrors) Error() string {
+	messages := []string{}
+	for _, err := range pe {
+		messages = append(messages, err.Message)
+	}
+	return strings.Join(messages, ", ")
+}
+
+type parameterParser struct {
+	values url.Values
+	errs   parameterErrors
+}
+
+func (pp *parameterParser) push(name, value string, err error) {
+	if err != nil {
+		pp.errs = append(pp.errs, &parameterError{Parameter: name, Value: value, Message: err.Error()})
+	}
+}
+
+func (pp *parameterParser) stringValue(name string, dest *string) {
+	if value := pp.values.Get(name); value != "" {
+		*dest = value
+	}
+}
+
+func (pp *parameterParser) boolValue(name string, dest *bool) {
+	value := pp.values.Get(name)
+	if value == "" {
+		return
+	}
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		pp.push(name, value, fmt.Errorf("%s: %s must be true or false", value, name))
+		return
+	}
+	*dest = flag
+}
+
+func (pp *parameterParser) intValue(name string, dest *int, min, max int) {
+	value := pp.values.Get(name)
+	if value == "" {
+		return
+	}
+	number, err := strconv.Atoi(value)
+	switch {
+	case err != nil:
+		pp.push(name, value, fmt.Errorf("%s: %s must be the number", value, name))
+	case number < min || number > max:
+		pp.push(name, value, fmt.Errorf("%s: %s must be between %d and %d", value, name, min, max))
+	default:
+		*dest = number
+	}
+}
+
+// flagValues sets the flags by the comma separated values of the given parameter, which may be given multiple times.
+func (pp *parameterParser) flagValues(name string, flags map[string][]*bool) {
+	for _, item := range pp.values[name] {
+		for _, value := range strings.Split(item, ",") {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			dests, ok := flags[strings.ToLower(value)]
+			if !ok {
+				pp.push(name, value, fmt.Errorf("%s: unknown %s value", value, name))
+				continue
+			}
+			for _, dest := range dests {
+				*dest = true
+			}
+		}
+	}
+}
+
+// parseRequestOptions parses the query parameters of the given request.
+// All of the invalid parameters are reported as parameterErrors.
+func parseRequestOptions(req *http.Request) (*requestOptions, error) {
+	opts := &requestOptions{
+		reads:    &wildcat.ReadOptions{},
+		runtime:  &wildcat.RuntimeOptions{ShowProgress: false, ThreadNumber: 10, StoreContent: false},
+		count:    &countingOptions{},
+		printer:  &printerOptions{format: "default"},
+		fileName: "<request>",
+	}
+	pp := &parameterParser{values: req.URL.Query(), errs: parameterErrors{}}
+	pp.stringValue("file-name", &opts.fileName)
+	parseReadingParams(pp, opts)
+	parsePrintingParams(pp, opts)
+	format, err := negotiateFormat(req)
+	if err != nil {
+		if _, ok := err.(*statusError); ok {
+			return nil, err
+		}
+		pp.push("format", pp.values.Get("format"), err)
+	}
+	opts.format = format
+	if len(pp.errs) > 0 {
+		return nil, pp.errs
+	}
+	return opts, nil
+}
+
+func parseReadingParams(pp *parameterParser, opts *requestOptions) {
+	reads, count, runtime := opts.reads, opts.count, opts.runtime
+	pp.flagValues("readAs", map[string][]*bool{
+		"file-list":  {&reads.FileList},
+		"no-extract": {&reads.NoExtract},
+		"no-ignore":  {&reads.NoIgnore},
+		"all":        {&reads.AllFiles},
+	})
+	pp.flagValues("count", map[string][]*bool{
+		"lines":      {&count.lines},
+		"words":      {&count.words},
+		"chars":      {&count.characters},
+		"characters": {&count.characters},
+		"bytes":      {&count.bytes},
+		"all":        {&count.lines, &count.words, &count.characters, &count.bytes},
+	})
+	threads := int(runtime.ThreadNumber)
+	pp.intValue("threads", &threads, 1, maxRequestThreads)
+	runtime.ThreadNumber = int64(threads)
+	pp.boolValue("store-content", &runtime.StoreContent)
+	pp.boolValue("fail-fast", &runtime.FailFast)
+	pp.intValue("max-errors", &runtime.MaxErrors, 0, math.MaxInt32)
+}
+
+func parsePrintingParams(pp *parameterParser, opts *requestOptions) {
+	printer := opts.printer
+	pp.boolValue("humanize", &printer.humanize)
+	pp.stringValue("sort", &printer.sort)
+	pp.intValue("top", &printer.top, 0, math.MaxInt32)
+	if _, err := wildcat.ParseSortOrder(printer.sort, printer.top); err != nil {
+		pp.push("sort", printer.sort, err)
+	}
+	pp.boolValue("subtotal", &printer.subtotal)
+	pp.intValue("depth", &printer.depth, 0, math.MaxInt32)
+	pp.boolValue("stats", &printer.stats)
+	pp.stringValue("histogram", &printer.histogram)
+	if err := validateHistogram(&options{count: opts.count, printer: printer}); err != nil {
+		pp.push("histogram", printer.histogram, err)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// errPrivateAddress is the error of the url targets on the private addresses, which are refused without --allow-private-urls.
+var errPrivateAddress = stderrors.New("the private address is not allowed")
+
+// privateNetworks are the networks refused in addition to the loopback, the link-local, and the unspecified addresses.
+var privateNetworks = parseNetworks(
+	"10.0.0.0/8",     // private network (RFC 1918)
+	"172.16.0.0/12",  // private network (RFC 1918)
+	"192.168.0.0/16", // private network (RFC 1918)
+	"100.64.0.0/10",  // shared address space (RFC 6598)
+	"fc00::/7",       // unique local address (RFC 4193)
+)
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := []*net.IPNet{}
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// isPublicIP checks the given ip is reachable from the internet, that is, the url targets may read it.
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// refusePrivateAddress is the control function of net.Dialer refusing the connections to the private addresses.
+// The addresses are examined after resolving the host names, therefore, the redirects and the dns rebinding are also refused.
+func refusePrivateAddress(network, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("%s: %w", host, errPrivateAddress)
+	}
+	return nil
+}
+
+// newRemoteClient creates the http client for the url targets of the requests.
+// The client refuses the loopback, the private, and the link-local addresses (e.g., the cloud metadata servers), unless allowPrivate is true.
+func newRemoteClient(allowPrivate bool) *http.Client {
+	if allowPrivate {
+		return http.DefaultClient
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: refusePrivateAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Transport: transport}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net"
+	"testing"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	testdata := []struct {
+		giveIP string
+		wont   bool
+	}{
+		{"8.8.8.8", true},
+		{"2001:4860:4860::8888", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"172.32.0.1", true},
+		{"192.168.1.1", false},
+		{"100.64.0.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::ffff:127.0.0.1", false},
+	}
+	for _, td := range testdata {
+		if got := isPublicIP(net.ParseIP(td.giveIP)); got != td.wont {
+			t.Errorf("isPublicIP(%s) did not match, wont %v, got %v", td.giveIP, td.wont, got)
+		}
+	}
+}
@@ -2,9 +2,14 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"mime/multipart"
+	"net"
 	"net/http"
+	"path/filepath"
 	"strings"
 
 	"github.com/tamada/wildcat/errors"
@@ -16,23 +21,20 @@ import (
 	"github.com/tamada/wildcat/logger"
 )
 
+// multipartEntry is the file part of the multipart request body, which is counted while the body streams in.
 type multipartEntry struct {
-	header *multipart.FileHeader
+	part   *multipart.Part
 	index  *wildcat.Order
 	reader iowrapper.ReadCloseTypeParser
 }
 
 func (me *multipartEntry) Name() string {
-	return me.header.Filename
+	return me.part.FileName()
 }
 
 func (me *multipartEntry) Open() (iowrapper.ReadCloseTypeParser, error) {
-	if me.reader != nil {
-		return me.reader, nil
-	}
-	reader, err := me.header.Open()
-	if err == nil {
-		me.reader = iowrapper.NewReader(reader)
+	if me.reader == nil {
+		me.reader = iowrapper.NewReader(me.part)
 	}
 	return me.reader, nil
 }
@@ -45,17 +47,8 @@ func (me *multipartEntry) Count(generator wildcat.Generator) *wildcat.Either {
 	return wildcat.CountDefault(me, generator())
 }
 
-func parseQueryParams(req *http.Request) *wildcat.ReadOptions {
-	values := req.URL.Query()
-	opts := &wildcat.ReadOptions{}
-	params := strings.Join(values["readAs"], ",")
-	if strings.Contains(params, "file-list") {
-		opts.FileList = true
-	}
-	if strings.Contains(params, "no-extract") {
-		opts.NoExtract = true
-	}
-	return opts
+func (me *multipartEntry) CountContext(ctx context.Context, generator wildcat.Generator) *wildcat.Either {
+	return wildcat.CountDefaultContext(ctx, me, generator())
 }
 
 type myEntry struct {
@@ -83,83 +76,206 @@ func (me *myEntry) Count(generator wildcat.Generator) *wildcat.Either {
 	return wildcat.CountDefault(me, generator())
 }
 
-func createResultJSON(rs *wildcat.ResultSet, sizer wildcat.Sizer) []byte {
+func (me *myEntry) CountContext(ctx context.Context, generator wildcat.Generator) *wildcat.Either {
+	return wildcat.CountDefaultContext(ctx, me, generator())
+}
+
+func createResult(rs *wildcat.ResultSet, format *responseFormat, sizer wildcat.Sizer) []byte {
 	buffer := bytes.NewBuffer([]byte{})
-	printer := wildcat.NewPrinter(buffer, "json", sizer)
+	printer := wildcat.NewPrinter(buffer, format.name, sizer)
 	rs.Print(printer)
 	return buffer.Bytes()
 }
 
 func isError(err error) bool {
 	center, ok := err.(*errors.Center)
-	return err != nil && (ok && !center.IsEmpty())
+	return err != nil && (!ok || !center.IsEmpty())
 }
 
-func respond(rs *wildcat.ResultSet, err error, res http.ResponseWriter, sizer wildcat.Sizer) {
-	updateHeader(res)
+func respond(rs *wildcat.ResultSet, err error, res http.ResponseWriter, format *responseFormat, sizer wildcat.Sizer) {
 	if isError(err) {
-		respondImpl(res, 400, []byte(fmt.Sprintf(`{"message":"%s"}`, err.Error())))
+		respondError(res, err)
 	} else {
-		respondImpl(res, 200, createResultJSON(rs, sizer))
+		res.Header().Set("Content-Type", format.contentType())
+		respondImpl(res, 200, createResult(rs, format, sizer))
 	}
 }
 
+// respondError responds the given error in json, the status code is 400 unless the error has its status code,
+// or the error is caused by reading the paths out of the root directory (403).
+// The parameterErrors are responded with the list of the invalid parameters.
+func respondError(res http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	var se *statusError
+	switch {
+	case stderrors.As(err, &se):
+		status = se.status
+	case stderrors.Is(err, errBodyTooLarge):
+		status = http.StatusRequestEntityTooLarge
+	case stderrors.Is(err, context.DeadlineExceeded):
+		status = http.StatusServiceUnavailable
+	case stderrors.Is(err, errors.ErrPermission):
+		status = http.StatusForbidden
+	}
+	message, _ := json.Marshal(err.Error())
+	body := fmt.Sprintf(`{"message":%s}`, message)
+	var pe parameterErrors
+	if stderrors.As(err, &pe) {
+		errs, _ := json.Marshal(pe)
+		body = fmt.Sprintf(`{"message":"invalid parameters","errors":%s}`, errs)
+	}
+	res.Header().Set("Content-Type", jsonFormat.contentType())
+	respondImpl(res, status, []byte(body))
+}
+
 func respondImpl(res http.ResponseWriter, statusCode int, message []byte) {
 	res.WriteHeader(statusCode)
 	res.Write(message)
 }
 
-func countsBody(res http.ResponseWriter, req *http.Request, opts *wildcat.ReadOptions, runtimeOpts *wildcat.RuntimeOptions) (*wildcat.ResultSet, error) {
-	wc := wildcat.NewWildcat(opts, runtimeOpts, wildcat.DefaultGenerator)
-	fileName := req.URL.Query().Get("file-name")
-	if fileName == "" {
-		fileName = "<request>"
-	}
-	entry := &myEntry{name: fileName, reader: iowrapper.NewReader(req.Body)}
-	return wc.CountEntries([]wildcat.Entry{entry})
+func countsBody(res http.ResponseWriter, req *http.Request, opts *requestOptions) (*wildcat.ResultSet, error) {
+	wc := opts.newWildcat()
+	entry := &myEntry{name: opts.fileName, reader: iowrapper.NewReader(req.Body)}
+	return wc.CountEntriesContext(req.Context(), []wildcat.Entry{entry})
+}
+
+// restServer serves the REST API of wildcat by the given server options.
+type restServer struct {
+	opts         *serverOptions
+	jobs         *jobStore
+	requests     chan struct{}
+	metrics      *serverMetrics
+	client       *http.Client
+	shuttingDown int32
+}
+
+// targetsRequest is the json request body for counting the urls and the paths on the server.
+type targetsRequest struct {
+	Targets []string `json:"targets"`
 }
 
-func counts(res http.ResponseWriter, req *http.Request) {
+type countFunc func(http.ResponseWriter, *http.Request, *requestOptions) (*wildcat.ResultSet, error)
+
+func (server *restServer) counts(res http.ResponseWriter, req *http.Request) {
 	logger.Infof("counts: %s\n", req.URL)
+	opts, err := server.parseRequestOptions(req)
+	if err != nil {
+		respondError(res, err)
+		return
+	}
+	rs, err := server.countFunc(req)(res, req, opts)
+	server.metrics.observeResults(rs)
+	if rs != nil {
+		opts.printer.applyTo(rs)
+	}
+	respond(rs, err, res, opts.format, opts.sizer())
+}
+
+// parseRequestOptions parses the query parameters of the given request, and applies the limits of the server.
+// The paths on the server are confined to --root in every request (e.g., the paths in the file lists), and forbidden without it.
+func (server *restServer) parseRequestOptions(req *http.Request) (*requestOptions, error) {
+	opts, err := parseRequestOptions(req)
+	if err != nil {
+		return nil, err
+	}
+	if opts.runtime.StoreContent && server.opts.storeDir == "" {
+		return nil, parameterErrors{&parameterError{Parameter: "store-content", Value: req.URL.Query().Get("store-content"),
+			Message: "store-content is not allowed, the server requires --store-dir option"}}
+	}
+	opts.reads.MaxExpansionSize = int64(server.opts.maxExpansionSize)
+	opts.reads.Root = server.opts.root
+	opts.reads.NoLocalFiles = true
+	opts.runtime.StoreDir = server.opts.storeDir
+	opts.runtime.HTTPClient = server.client
+	return opts, nil
+}
+
+// countFunc returns the function for counting the request body by its content type.
+func (server *restServer) countFunc(req *http.Request) countFunc {
 	contentType := req.Header.Get("Content-Type")
 	handlers := []struct {
 		contentType string
-		execFunc    func(http.ResponseWriter, *http.Request, *wildcat.ReadOptions, *wildcat.RuntimeOptions) (*wildcat.ResultSet, error)
+		execFunc    countFunc
 	}{
 		{"multipart/form-data", countsMultipartBody},
-		{"*", countsBody},
+		{"application/json", server.countsTargets},
 	}
-	opts := parseQueryParams(req)
-	sizer := wildcat.BuildSizer(false)
-	runtimeOpts := &wildcat.RuntimeOptions{ShowProgress: false, ThreadNumber: 10, StoreContent: false}
 	for _, handler := range handlers {
-		if handler.contentType == "*" || strings.HasPrefix(contentType, handler.contentType) {
-			rs, err := handler.execFunc(res, req, opts, runtimeOpts)
-			respond(rs, err, res, sizer)
-			break
+		if strings.HasPrefix(contentType, handler.contentType) {
+			return handler.execFunc
 		}
 	}
+	return countsBody
 }
 
-func generateEntriesFromMultipart(req *http.Request) []wildcat.Entry {
+// nextMultipartEntry returns the function which returns the file parts of the given multipart reader in order.
+// The form values without the file name are skipped.
+func nextMultipartEntry(reader *multipart.Reader) func() (wildcat.Entry, error) {
 	index := wildcat.NewOrder()
-	entries := []wildcat.Entry{}
-	for _, headers := range req.MultipartForm.File {
-		for _, header := range headers {
-			entries = append(entries, &multipartEntry{header: header, index: index})
-			index = index.Next()
+	return func() (wildcat.Entry, error) {
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				return nil, err
+			}
+			if part.FileName() != "" {
+				entry := &multipartEntry{part: part, index: index}
+				index = index.Next()
+				return entry, nil
+			}
 		}
 	}
-	return entries
 }
 
-func countsMultipartBody(res http.ResponseWriter, req *http.Request, opts *wildcat.ReadOptions, runtimeOpts *wildcat.RuntimeOptions) (*wildcat.ResultSet, error) {
-	if err := req.ParseMultipartForm(32 << 20); err != nil {
-		return nil, fmt.Errorf("ParseMultpartForm: %w", err)
+// countsMultipartBody counts each file part of the multipart request body as it streams in,
+// therefore, the uploaded files are not buffered in memory nor temporary files (except zip files, which need random access).
+func countsMultipartBody(res http.ResponseWriter, req *http.Request, opts *requestOptions) (*wildcat.ResultSet, error) {
+	reader, err := req.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("MultipartReader: %w", err)
 	}
-	entries := generateEntriesFromMultipart(req)
-	wc := wildcat.NewWildcat(opts, runtimeOpts, wildcat.DefaultGenerator)
-	return wc.CountEntries(entries)
+	wc := opts.newWildcat()
+	return wc.CountStreamContext(req.Context(), nextMultipartEntry(reader))
+}
+
+// countsTargets counts the targets given by the json request body.
+// The targets are urls, or the paths under the root directory if the server allows it by --root option.
+func (server *restServer) countsTargets(res http.ResponseWriter, req *http.Request, opts *requestOptions) (*wildcat.ResultSet, error) {
+	request := &targetsRequest{}
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(request); err != nil {
+		return nil, fmt.Errorf("invalid request body: %w", err)
+	}
+	if len(request.Targets) == 0 {
+		return nil, fmt.Errorf("targets must not be empty")
+	}
+	targets := []string{}
+	for _, target := range request.Targets {
+		path, err := server.resolveTarget(target)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, path)
+	}
+	wc := opts.newWildcat()
+	return wc.CountAllContext(req.Context(), wildcat.NewArgf(targets, opts.reads, opts.runtime))
+}
+
+// resolveTarget returns the url as it is, or the cleaned path, which the counting resolves against the root directory.
+// The absolute paths, and the paths out of the root directory (e.g., "../etc/passwd") are forbidden.
+func (server *restServer) resolveTarget(target string) (string, error) {
+	if wildcat.IsURL(target) {
+		return target, nil
+	}
+	if server.opts.root == "" {
+		return "", &statusError{status: http.StatusForbidden, err: fmt.Errorf("%s: counting the paths on the server is not allowed", target)}
+	}
+	path := filepath.Clean(filepath.FromSlash(target))
+	if filepath.IsAbs(path) || path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator)) {
+		return "", &statusError{status: http.StatusForbidden, err: fmt.Errorf("%s: the path must be relative to the root directory", target)}
+	}
+	return path, nil
 }
 
 func wrapHandler(h http.Handler) http.HandlerFunc {
@@ -169,32 +285,37 @@ func wrapHandler(h http.Handler) http.HandlerFunc {
 	}
 }
 
-func updateHeader(w http.ResponseWriter) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "POST,OPTIONS")
-	w.Header().Set("Access-Control-Allow-Credentials", "true")
-}
-
-func optionsHandler(res http.ResponseWriter, req *http.Request) {
-	updateHeader(res)
-	res.Header().Set("Access-Control-Request-Method", "POST")
-	res.Header().Set("Access-Control-Allow-Headers", "origin, accept, X-PINGOTHER, Content-Type")
-	res.WriteHeader(200)
-	res.Write([]byte{})
+func registerHandlers(router *mux.Router, server *restServer) {
+	router.HandleFunc("/counts", server.counts).Methods("POST")
+	router.HandleFunc("/jobs", server.createJob).Methods("POST")
+	router.HandleFunc("/jobs/{id}", server.jobStatus).Methods("GET")
+	router.HandleFunc("/jobs/{id}", server.deleteJob).Methods("DELETE")
+	router.HandleFunc("/jobs/{id}/results", server.jobResults).Methods("GET")
+	router.PathPrefix("/").HandlerFunc(optionsHandler).Methods("OPTIONS")
 }
 
-func registerHandlers(router *mux.Router) {
-	router.HandleFunc("/counts", counts).Methods("POST")
-	router.HandleFunc("/counts", optionsHandler).Methods("OPTIONS")
+func newRestServer(opts *serverOptions) *restServer {
+	return &restServer{opts: opts, jobs: newJobStore(opts.jobTTL), requests: newRequestSemaphore(opts.maxRequests), metrics: newServerMetrics(), client: newRemoteClient(opts.allowPrivateURLs)}
 }
 
-func createRestAPIServer() *mux.Router {
+// router creates the router of the api, the health and the metrics endpoints, and the documents.
+// The health and the metrics endpoints are out of the api, therefore, they are neither authenticated nor limited.
+func (server *restServer) router() *mux.Router {
 	router := mux.NewRouter()
-	registerHandlers(router.PathPrefix("/wildcat/api/").Subrouter())
+	router.HandleFunc("/healthz", server.healthHandler).Methods("GET")
+	router.HandleFunc("/readyz", server.readyHandler).Methods("GET")
+	router.HandleFunc("/metrics", server.metricsHandler).Methods("GET")
+	api := router.PathPrefix("/wildcat/api/").Subrouter()
+	api.Use(server.metrics.middleware, newCORSPolicy(server.opts).middleware, newAuthenticator(server.opts.tokens).authenticate, server.limitRequests)
+	registerHandlers(api, server)
 	router.PathPrefix("/wildcat").Handler(fileServer())
 	return router
 }
 
+func createRestAPIServer(opts *serverOptions) *mux.Router {
+	return newRestServer(opts).router()
+}
+
 func fileServer() http.Handler {
 	dirs := []string{
 		"docs/public",
@@ -209,14 +330,60 @@ func fileServer() http.Handler {
 	}
 	return nil
 }
-func (server *serverOptions) start(router *mux.Router) int {
-	logger.Infof("Listen server at port %d", server.port)
-	logger.Infof("start shutdown: %s\n", http.ListenAndServe(fmt.Sprintf(":%d", server.port), router))
+
+// start serves the given rest server until SIGINT or SIGTERM, and drains the requests in flight before returning.
+func (server *serverOptions) start(rest *restServer) int {
+	listener, err := server.listen()
+	if err != nil {
+		logger.Warnf("start server: %s", err)
+		return 1
+	}
+	httpServer := server.newHTTPServer(rest.router())
+	if httpServer.TLSConfig, err = server.tlsConfig(); err != nil {
+		listener.Close()
+		logger.Warnf("start server: %s", err)
+		return 1
+	}
+	done, stop := make(chan struct{}), make(chan struct{})
+	defer close(stop)
+	go rest.jobs.sweepPeriodically(stop)
+	go server.shutdownOnSignal(rest, httpServer, done)
+	logger.Infof("Listen server at %s", server.listenURL())
+	if err := serve(httpServer, listener); err != http.ErrServerClosed {
+		logger.Warnf("start server: %s", err)
+		return 1
+	}
+	<-done
+	logger.Infof("shutdown completed")
 	return 0
 }
 
+// serve serves over HTTPS if the given server has the tls config, otherwise, over plain HTTP.
+func serve(httpServer *http.Server, listener net.Listener) error {
+	if httpServer.TLSConfig != nil {
+		return httpServer.ServeTLS(listener, "", "")
+	}
+	return httpServer.Serve(listener)
+}
+
+func (server *serverOptions) listenURL() string {
+	if server.unixSocket != "" {
+		return "unix:" + server.unixSocket
+	}
+	if server.isTLS() {
+		return "https://" + server.address()
+	}
+	return "http://" + server.address()
+}
+
 func (server *serverOptions) launchServer() int {
 	logger.SetLevel(logger.INFO)
-	router := createRestAPIServer()
-	return server.start(router)
+	if err := server.loadTokens(); err != nil {
+		logger.Warnf("loading api tokens: %s", err)
+		return 1
+	}
+	if len(server.tokens) > 0 {
+		logger.Infof("api requires the bearer tokens (%d tokens)", len(server.tokens))
+	}
+	return server.start(newRestServer(server))
 }
@@ -3,9 +3,12 @@ package main
 import (
 	"bytes"
 	"io"
+	"io/ioutil"
 	"mime/multipart"
+	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -28,7 +31,12 @@ func TestParseQueryParam(t *testing.T) {
 	}
 	for _, td := range testdata {
 		req := httptest.NewRequest("POST", td.giveURL, nil)
-		opts := parseQueryParams(req)
+		ro, err := parseRequestOptions(req)
+		if err != nil {
+			t.Errorf("%s: parseRequestOptions failed: %v", td.giveURL, err)
+			continue
+		}
+		opts := ro.reads
 		if opts.FileList != td.wontFileListFlag {
 			t.Errorf("%s: parseOptions failed, fileList: wont %v, got %v", td.giveURL, td.wontFileListFlag, opts.FileList)
 		}
@@ -52,7 +60,7 @@ func TestBasicRequest(t *testing.T) {
 		{"/wildcat/api/counts?file-name=wc.jar&readAs=no-extract", "../../testdata/archives/wc.jar", 200, `"results":[{"filename":"wc.jar","lines":"5","words":"62","characters":"1,054","bytes":"1,080"}]`},
 	}
 
-	router := createRestAPIServer()
+	router := createRestAPIServer(&serverOptions{})
 	for _, td := range testdata {
 		reader, _ := os.Open(td.giveContentPath)
 		defer reader.Close()
@@ -70,6 +78,33 @@ func TestBasicRequest(t *testing.T) {
 	}
 }
 
+func TestSortQuery(t *testing.T) {
+	testdata := []struct {
+		giveURL    string
+		wontStatus int
+		wontSuffix string
+	}{
+		{"/wildcat/api/counts?sort=bytes&top=1", 200, `"results":[{"filename":"<request>!london_bridge_is_broken_down.txt","lines":"59","words":"260","characters":"1,341","bytes":"1,341"},{"filename":"total","lines":"78","words":"312","characters":"1,601","bytes":"1,781"}]`},
+		{"/wildcat/api/counts?sort=name:desc&top=2", 200, `"results":[{"filename":"<request>!london_bridge_is_broken_down.txt","lines":"59","words":"260","characters":"1,341","bytes":"1,341"},{"filename":"<request>!ja/sakura_sakura.txt","lines":"15","words":"26","characters":"118","bytes":"298"},{"filename":"total"`},
+		{"/wildcat/api/counts?sort=unknown", 400, `{"parameter":"sort","value":"unknown","message":"unknown: unknown sort key"}`},
+		{"/wildcat/api/counts?top=many", 400, `{"parameter":"top","value":"many","message":"many: top must be the number"}`},
+	}
+	router := createRestAPIServer(&serverOptions{})
+	for _, td := range testdata {
+		reader, _ := os.Open("../../testdata/archives/wc.jar")
+		defer reader.Close()
+		req := httptest.NewRequest("POST", td.giveURL, reader)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != td.wontStatus {
+			t.Errorf("%s: status code did not match, wont %d, got %d", td.giveURL, td.wontStatus, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), td.wontSuffix) {
+			t.Errorf("%s: response body did not match,\nwont %s,\ngot  %s", td.giveURL, td.wontSuffix, rec.Body.String())
+		}
+	}
+}
+
 func TestFileList(t *testing.T) {
 	testdata := []struct {
 		giveURL    string
@@ -82,7 +117,7 @@ func TestFileList(t *testing.T) {
 	}
 	content := `https://github.com/tamada/wildcat/raw/main/testdata/archives/wc.jar
 https://github.com/tamada/wildcat/raw/main/testdata/wc/humpty_dumpty.txt`
-	router := createRestAPIServer()
+	router := createRestAPIServer(&serverOptions{})
 	for _, td := range testdata {
 		req := httptest.NewRequest("POST", td.giveURL, strings.NewReader(content))
 		rec := httptest.NewRecorder()
@@ -114,7 +149,7 @@ func TestMultipart(t *testing.T) {
 		{"/wildcat/api/counts", 200, `"results":[{"filename":"humpty_dumpty.txt","lines":"4","words":"26","characters":"142","bytes":"142"},{"filename":"wc.jar!humpty_dumpty.txt","lines":"4","words":"26","characters":"142","bytes":"142"},{"filename":"wc.jar!ja/","lines":"0","words":"0","characters":"0","bytes":"0"},{"filename":"wc.jar!ja/sakura_sakura.txt","lines":"15","words":"26","characters":"118","bytes":"298"},{"filename":"wc.jar!london_bridge_is_broken_down.txt","lines":"59","words":"260","characters":"1,341","bytes":"1,341"},{"filename":"total","lines":"82","words":"338","characters":"1,743","bytes":"1,923"}]`},
 		{"/wildcat/api/counts?readAs=no-extract", 200, `"results":[{"filename":"humpty_dumpty.txt","lines":"4","words":"26","characters":"142","bytes":"142"},{"filename":"wc.jar","lines":"5","words":"62","characters":"1,054","bytes":"1,080"},{"filename":"total","lines":"9","words":"88","characters":"1,196","bytes":"1,222"}]`},
 	}
-	router := createRestAPIServer()
+	router := createRestAPIServer(&serverOptions{})
 	content := bytes.NewBuffer([]byte{})
 	writer := multipart.NewWriter(content)
 	addPart(writer, "humpty_dumpty.txt", "../../testdata/wc/humpty_dumpty.txt")
@@ -134,3 +169,206 @@ func TestMultipart(t *testing.T) {
 		}
 	}
 }
+
+func TestMultipartStream(t *testing.T) {
+	reader, pipe := io.Pipe()
+	writer := multipart.NewWriter(pipe)
+	go func() {
+		addPart(writer, "wc.tar", "../../testdata/archives/wc.tar")
+		writer.WriteField("comment", "form values are not counted")
+		addPart(writer, "humpty_dumpty.txt", "../../testdata/wc/humpty_dumpty.txt")
+		writer.Close()
+		pipe.Close()
+	}()
+	req := httptest.NewRequest("POST", "/wildcat/api/counts", reader)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+	createRestAPIServer(&serverOptions{}).ServeHTTP(rec, req)
+	wont := `"results":[{"filename":"wc.tar!humpty_dumpty.txt","lines":"4","words":"26","characters":"142","bytes":"142"},{"filename":"wc.tar!ja/","lines":"0","words":"0","characters":"0","bytes":"0"},{"filename":"wc.tar!ja/sakura_sakura.txt","lines":"15","words":"26","characters":"118","bytes":"298"},{"filename":"wc.tar!london_bridge_is_broken_down.txt","lines":"59","words":"260","characters":"1,341","bytes":"1,341"},{"filename":"humpty_dumpty.txt","lines":"4","words":"26","characters":"142","bytes":"142"},`
+	if rec.Code != 200 || !strings.Contains(rec.Body.String(), wont) {
+		t.Errorf("streaming multipart did not match, got %d,\nwont %s,\ngot  %s", rec.Code, wont, rec.Body.String())
+	}
+}
+
+func TestResponseFormat(t *testing.T) {
+	testdata := []struct {
+		giveURL         string
+		giveAccept      string
+		wontStatus      int
+		wontContentType string
+		wontContains    string
+	}{
+		{"/wildcat/api/counts", "", 200, "application/json", `{"filename":"<request>","lines":"59","words":"260","characters":"1,341","bytes":"1,341"}`},
+		{"/wildcat/api/counts", "*/*", 200, "application/json", `"results":[`},
+		{"/wildcat/api/counts", "text/csv", 200, "text/csv", "entry,<request>,59,260,1341,1341\n"},
+		{"/wildcat/api/counts", "application/xml;q=0.5, application/x-ndjson", 200, "application/x-ndjson", `{"filename":"<request>","lines":"59","words":"260","characters":"1,341","bytes":"1,341"}` + "\n"},
+		{"/wildcat/api/counts", "text/html, application/xml;q=0.9", 200, "application/xml", `<result><file-name>&lt;request&gt;</file-name><lines>59</lines>`},
+		{"/wildcat/api/counts", "text/*", 200, "text/plain", `   59   260      1,341 1,341 <request>`},
+		{"/wildcat/api/counts?format=csv", "application/json", 200, "text/csv", "type,name,lines,words,characters,bytes\n"},
+		{"/wildcat/api/counts?format=default&humanize=true", "", 200, "text/plain", `   59   260      1,341 1.3 kB <request>`},
+		{"/wildcat/api/counts?humanize=true", "", 200, "application/json", `"bytes":"1.3 kB"`},
+		{"/wildcat/api/counts?format=yaml", "", 400, "application/json", `{"parameter":"format","value":"yaml","message":"yaml: unknown format"}`},
+		{"/wildcat/api/counts?humanize=maybe", "", 400, "application/json", `{"parameter":"humanize","value":"maybe","message":"maybe: humanize must be true or false"}`},
+		{"/wildcat/api/counts", "text/html, application/json;q=0", 406, "application/json", `not acceptable media types`},
+	}
+	router := createRestAPIServer(&serverOptions{})
+	for _, td := range testdata {
+		reader, _ := os.Open("../../testdata/wc/london_bridge_is_broken_down.txt")
+		defer reader.Close()
+		req := httptest.NewRequest("POST", td.giveURL, reader)
+		if td.giveAccept != "" {
+			req.Header.Set("Accept", td.giveAccept)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != td.wontStatus {
+			t.Errorf("%s (%s): status code did not match, wont %d, got %d", td.giveURL, td.giveAccept, td.wontStatus, rec.Code)
+		}
+		if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, td.wontContentType) {
+			t.Errorf("%s (%s): content type did not match, wont %s, got %s", td.giveURL, td.giveAccept, td.wontContentType, got)
+		}
+		if !strings.Contains(rec.Body.String(), td.wontContains) {
+			t.Errorf("%s (%s): response body did not match,\nwont %s,\ngot  %s", td.giveURL, td.giveAccept, td.wontContains, rec.Body.String())
+		}
+	}
+}
+
+func TestOptionParity(t *testing.T) {
+	testdata := []struct {
+		giveURL      string
+		wontStatus   int
+		wontContains string
+	}{
+		{"/wildcat/api/counts?count=lines,words", 200, `{"filename":"total","lines":"78","words":"312"}`},
+		{"/wildcat/api/counts?count=bytes&count=chars", 200, `{"filename":"total","characters":"1,601","bytes":"1,781"}`},
+		{"/wildcat/api/counts?count=all&threads=1", 200, `{"filename":"total","lines":"78","words":"312","characters":"1,601","bytes":"1,781"}`},
+		{"/wildcat/api/counts?file-name=wc.jar&subtotal=true&count=lines", 200, `{"filename":"wc.jar","type":"subtotal","lines":"78"}`},
+		{"/wildcat/api/counts?count=lines&stats=true", 200, `"statistics":{"entries":4,"lines":{"min":"0","max":"59",`},
+		{"/wildcat/api/counts?count=lines&histogram=lines", 200, `"histogram":{"type":"lines","bins":[`},
+		{"/wildcat/api/counts?readAs=no-ignore,all&fail-fast=true&max-errors=3&store-content=false", 200, `"results":[`},
+		{"/wildcat/api/counts?count=pages", 400, `{"parameter":"count","value":"pages","message":"pages: unknown count value"}`},
+		{"/wildcat/api/counts?readAs=hidden", 400, `{"parameter":"readAs","value":"hidden","message":"hidden: unknown readAs value"}`},
+		{"/wildcat/api/counts?threads=0", 400, `{"parameter":"threads","value":"0","message":"0: threads must be between 1 and 64"}`},
+		{"/wildcat/api/counts?depth=-1", 400, `{"parameter":"depth","value":"-1","message":"-1: depth must be between 0 and 2147483647"}`},
+		{"/wildcat/api/counts?count=lines&histogram=bytes", 400, `{"parameter":"histogram","value":"bytes","message":"bytes: histogram type must be counted"}`},
+		{"/wildcat/api/counts?stats=yes&max-errors=many", 400, `{"message":"invalid parameters","errors":[{"parameter":"max-errors","value":"many","message":"many: max-errors must be the number"},{"parameter":"stats","value":"yes","message":"yes: stats must be true or false"}]}`},
+	}
+	router := createRestAPIServer(&serverOptions{})
+	for _, td := range testdata {
+		reader, _ := os.Open("../../testdata/archives/wc.jar")
+		defer reader.Close()
+		req := httptest.NewRequest("POST", td.giveURL, reader)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != td.wontStatus {
+			t.Errorf("%s: status code did not match, wont %d, got %d", td.giveURL, td.wontStatus, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), td.wontContains) {
+			t.Errorf("%s: response body did not match,\nwont %s,\ngot  %s", td.giveURL, td.wontContains, rec.Body.String())
+		}
+	}
+}
+
+func TestTargetsRequest(t *testing.T) {
+	remote := httptest.NewServer(http.FileServer(http.Dir("../../testdata")))
+	defer remote.Close()
+	testdata := []struct {
+		giveRoot         string
+		giveAllowPrivate bool
+		giveBody         string
+		wontStatus       int
+		wontContains     string
+	}{
+		{"../../testdata", false, `{"targets":["wc/humpty_dumpty.txt"]}`, 200, `{"filename":"wc/humpty_dumpty.txt","lines":"4","words":"26","characters":"142","bytes":"142"}`},
+		{"../../testdata", false, `{"targets":["wc/ja"]}`, 200, `{"filename":"wc/ja/sakura_sakura.txt","lines":"15"`},
+		{"../../testdata", true, `{"targets":["wc", "` + remote.URL + `/wc/humpty_dumpty.txt"]}`, 200, `{"filename":"total","lines":"82","words":"338","characters":"1,743","bytes":"1,923"}`},
+		{"", true, `{"targets":["` + remote.URL + `/wc/ja/sakura_sakura.txt"]}`, 200, `"lines":"15","words":"26","characters":"118","bytes":"298"}`},
+		{"", false, `{"targets":["` + remote.URL + `/wc/ja/sakura_sakura.txt"]}`, 400, `the private address is not allowed`},
+		{"", false, `{"targets":["http://169.254.169.254/latest/meta-data/"]}`, 400, `169.254.169.254: the private address is not allowed`},
+		{"", false, `{"targets":["wc/humpty_dumpty.txt"]}`, 403, `{"message":"wc/humpty_dumpty.txt: counting the paths on the server is not allowed"}`},
+		{"../../testdata", false, `{"targets":["../go.mod"]}`, 403, `{"message":"../go.mod: the path must be relative to the root directory"}`},
+		{"../../testdata", false, `{"targets":["wc/../../go.mod"]}`, 403, `{"message":"wc/../../go.mod: the path must be relative to the root directory"}`},
+		{"../../testdata", false, `{"targets":["/etc/passwd"]}`, 403, `{"message":"/etc/passwd: the path must be relative to the root directory"}`},
+		{"../../testdata", false, `{"targets":[]}`, 400, `{"message":"targets must not be empty"}`},
+		{"../../testdata", false, `{"files":["wc"]}`, 400, `{"message":"invalid request body: json: unknown field \"files\""}`},
+	}
+	for _, td := range testdata {
+		router := createRestAPIServer(&serverOptions{root: td.giveRoot, allowPrivateURLs: td.giveAllowPrivate})
+		req := httptest.NewRequest("POST", "/wildcat/api/counts", strings.NewReader(td.giveBody))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != td.wontStatus {
+			t.Errorf("%s: status code did not match, wont %d, got %d", td.giveBody, td.wontStatus, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), td.wontContains) {
+			t.Errorf("%s: response body did not match,\nwont %s,\ngot  %s", td.giveBody, td.wontContains, rec.Body.String())
+		}
+	}
+}
+
+func TestLocalPathsInFileList(t *testing.T) {
+	testdata := []struct {
+		giveRoot     string
+		giveBody     string
+		wontStatus   int
+		wontContains string
+	}{
+		{"", "/etc/passwd\n", 403, `{"message":"/etc/passwd: outside of the root directory"}`},
+		{"", "../../testdata/wc/humpty_dumpty.txt\n", 403, `outside of the root directory`},
+		{"../../testdata", "../go.mod\n", 403, `{"message":"../go.mod: outside of the root directory"}`},
+		{"../../testdata", "../../testdata/wc/humpty_dumpty.txt\n", 403, `{"message":"../../testdata/wc/humpty_dumpty.txt: outside of the root directory"}`},
+		{"../../testdata", "wc/humpty_dumpty.txt\n", 200, `{"filename":"wc/humpty_dumpty.txt","lines":"4","words":"26","characters":"142","bytes":"142"}`},
+		{"../../testdata", "wc/ja\n", 200, `{"filename":"wc/ja/sakura_sakura.txt","lines":"15"`},
+	}
+	for _, td := range testdata {
+		router := createRestAPIServer(&serverOptions{root: td.giveRoot})
+		req := httptest.NewRequest("POST", "/wildcat/api/counts?readAs=file-list", strings.NewReader(td.giveBody))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != td.wontStatus {
+			t.Errorf("%s: status code did not match, wont %d, got %d", td.giveBody, td.wontStatus, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), td.wontContains) {
+			t.Errorf("%s: response body did not match,\nwont %s,\ngot  %s", td.giveBody, td.wontContains, rec.Body.String())
+		}
+	}
+}
+
+func TestStoreContent(t *testing.T) {
+	remote := httptest.NewServer(http.FileServer(http.Dir("../../testdata")))
+	defer remote.Close()
+	storeDir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(storeDir, "sakura_sakura.txt"), []byte("existing\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	testdata := []struct {
+		giveStoreDir string
+		giveTarget   string
+		wontStatus   int
+		wontContains string
+		wontStored   string
+	}{
+		{"", "/wc/humpty_dumpty.txt", 400, `store-content is not allowed, the server requires --store-dir option`, ""},
+		{storeDir, "/wc/humpty_dumpty.txt", 200, `"lines":"4","words":"26","characters":"142","bytes":"142"}`, "humpty_dumpty.txt"},
+		{storeDir, "/wc/ja/sakura_sakura.txt", 400, `sakura_sakura.txt: file creation error`, ""},
+	}
+	for _, td := range testdata {
+		router := createRestAPIServer(&serverOptions{storeDir: td.giveStoreDir, allowPrivateURLs: true})
+		req := httptest.NewRequest("POST", "/wildcat/api/counts?store-content=true", strings.NewReader(`{"targets":["`+remote.URL+td.giveTarget+`"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != td.wontStatus || !strings.Contains(rec.Body.String(), td.wontContains) {
+			t.Errorf("%s: response did not match, wont %d %s, got %d %s", td.giveTarget, td.wontStatus, td.wontContains, rec.Code, rec.Body.String())
+		}
+		if td.wontStored != "" {
+			if _, err := os.Stat(filepath.Join(storeDir, td.wontStored)); err != nil {
+				t.Errorf("%s: content was not stored in the store directory: %v", td.giveTarget, err)
+			}
+		}
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(storeDir, "sakura_sakura.txt")); string(data) != "existing\n" {
+		t.Errorf("existing file was overwritten, got %s", string(data))
+	}
+}
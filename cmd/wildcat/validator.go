@@ -2,15 +2,135 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
 	"strings"
+
+	"github.com/tamada/wildcat"
 )
 
-func validateOptions(opts *options) error {
-	return validateFormat(opts.printer.format)
+func validateOptions(opts *options, runtime *wildcat.RuntimeOptions) error {
+	if err := validateFormat(opts.printer.format); err != nil {
+		return err
+	}
+	if err := validateColor(opts.printer.color); err != nil {
+		return err
+	}
+	if _, err := wildcat.ParseSortOrder(opts.printer.sort, opts.printer.top); err != nil {
+		return err
+	}
+	if opts.printer.depth < 0 {
+		return fmt.Errorf("%d: depth must be zero or positive", opts.printer.depth)
+	}
+	if runtime.MaxErrors < 0 {
+		return fmt.Errorf("%d: max errors must be zero or positive", runtime.MaxErrors)
+	}
+	if err := validateHistogram(opts); err != nil {
+		return err
+	}
+	if opts.server.root != "" && !wildcat.ExistDir(opts.server.root) {
+		return fmt.Errorf("%s: root directory not found", opts.server.root)
+	}
+	if opts.server.storeDir != "" && !wildcat.ExistDir(opts.server.storeDir) {
+		return fmt.Errorf("%s: store directory not found", opts.server.storeDir)
+	}
+	if opts.server.jobTTL <= 0 {
+		return fmt.Errorf("%s: job ttl must be positive", opts.server.jobTTL)
+	}
+	if err := validateTLS(opts.server); err != nil {
+		return err
+	}
+	if opts.server.tokenFile != "" && !wildcat.ExistFile(opts.server.tokenFile) {
+		return fmt.Errorf("%s: token file not found", opts.server.tokenFile)
+	}
+	if err := validateCORS(opts.server); err != nil {
+		return err
+	}
+	if opts.server.rateLimit < 0 {
+		return fmt.Errorf("%d: rate limit must be zero or positive", opts.server.rateLimit)
+	}
+	if opts.server.maxJobs < 0 {
+		return fmt.Errorf("%d: max jobs must be zero or positive", opts.server.maxJobs)
+	}
+	if opts.server.maxRequests < 0 {
+		return fmt.Errorf("%d: max requests must be zero or positive", opts.server.maxRequests)
+	}
+	if opts.server.requestTimeout < 0 || opts.server.shutdownTimeout < 0 || opts.server.jobTimeout < 0 {
+		return fmt.Errorf("timeouts must be zero or positive")
+	}
+	return validateTemplate(opts.printer)
+}
+
+func validateCORS(server *serverOptions) error {
+	if server.corsMaxAge < 0 {
+		return fmt.Errorf("%s: cors max age must be zero or positive", server.corsMaxAge)
+	}
+	if !server.corsCredentials {
+		return nil
+	}
+	for _, origin := range splitList(server.corsOrigins) {
+		if origin == "*" {
+			return fmt.Errorf("--cors-credentials does not allow any origins (*), specify the origins by --cors-origins")
+		}
+	}
+	return nil
+}
+
+func validateTLS(server *serverOptions) error {
+	if (server.tlsCert == "") != (server.tlsKey == "") {
+		return fmt.Errorf("--tls-cert and --tls-key must be given together")
+	}
+	if server.tlsSelfSigned && server.tlsCert != "" {
+		return fmt.Errorf("--tls-self-signed and --tls-cert are exclusive")
+	}
+	for _, file := range []string{server.tlsCert, server.tlsKey} {
+		if file != "" && !wildcat.ExistFile(file) {
+			return fmt.Errorf("%s: file not found", file)
+		}
+	}
+	return nil
+}
+
+func validateColor(givenColor string) error {
+	switch strings.ToLower(givenColor) {
+	case "auto", "always", "never":
+		return nil
+	default:
+		return fmt.Errorf("%s: invalid color mode", givenColor)
+	}
+}
+
+func validateHistogram(opts *options) error {
+	stats, err := opts.printer.statisticsOptions()
+	if err != nil {
+		return err
+	}
+	if stats.HistogramType != 0 && !opts.count.counterType().IsType(stats.HistogramType) {
+		return fmt.Errorf("%s: histogram type must be counted", opts.printer.histogram)
+	}
+	return nil
+}
+
+// validateTemplate parses the template of the template format, for failing before counting.
+func validateTemplate(opts *printerOptions) error {
+	if strings.ToLower(opts.format) != "template" {
+		return nil
+	}
+	if opts.template == "" {
+		return fmt.Errorf("template format requires --template option")
+	}
+	if opts.stats || opts.histogram != "" {
+		return fmt.Errorf("template format does not print the statistics, --stats and --histogram are not available")
+	}
+	text, err := wildcat.ReadTemplate(opts.template)
+	if err != nil {
+		return err
+	}
+	_, err = wildcat.NewTemplatePrinter(ioutil.Discard, text)
+	return err
 }
 
 func validateFormat(givenFormat string) error {
-	availableFormats := []string{"default", "csv", "json", "xml"}
+	availableFormats := []string{"default", "csv", "tsv", "json", "ndjson", "xml", "markdown", "html", "latex", "template", "tree"}
 	format := strings.ToLower(givenFormat)
 	return contains(format, availableFormats)
 }
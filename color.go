@@ -0,0 +1,22 @@
+package wildcat
+
+const (
+	resetStyle    = "\x1b[0m"
+	headerStyle   = "\x1b[1m"
+	totalStyle    = "\x1b[1;36m"
+	subtotalStyle = "\x1b[36m"
+	errorStyle    = "\x1b[31m"
+)
+
+// decorate wraps the given text by the given ANSI escape sequence, if color is true.
+func decorate(text, style string, color bool) string {
+	if !color || style == "" {
+		return text
+	}
+	return style + text + resetStyle
+}
+
+// ColoredError returns the given message decorated for the error, if color is true.
+func ColoredError(message string, color bool) string {
+	return decorate(message, errorStyle, color)
+}
@@ -8,7 +8,33 @@ __wildcat() {
 
     case "${prev}" in
         --format | -f)
-            COMPREPLY=($(compgen -W "default csv xml json" -- "${cur}"))
+            COMPREPLY=($(compgen -W "default csv tsv xml json ndjson markdown html latex template tree" -- "${cur}"))
+            return 0
+            ;;
+        --color)
+            COMPREPLY=($(compgen -W "auto always never" -- "${cur}"))
+            return 0
+            ;;
+        --sort)
+            COMPREPLY=($(compgen -W "order name lines words chars bytes" -- "${cur}"))
+            return 0
+            ;;
+        --histogram)
+            COMPREPLY=($(compgen -W "lines words chars bytes" -- "${cur}"))
+            return 0
+            ;;
+        --template | -T)
+            COMPREPLY=($(compgen -f -- "${cur}"))
+            return 0
+            ;;
+        --root | --store-dir)
+            compopt -o filenames
+            COMPREPLY=($(compgen -d -- "${cur}"))
+            return 0
+            ;;
+        --tls-cert | --tls-key | --token-file | --unix-socket)
+            compopt -o filenames
+            COMPREPLY=($(compgen -f -- "${cur}"))
             return 0
             ;;
         --output | -o)
@@ -16,7 +42,7 @@ __wildcat() {
             return 0
             ;;
     esac
-    opts=" -b --byte -l --line -c --character -w --word -n --no-ignore -N --no-extract-archive -@ --filelist -f --format -o --output -p --port -s --server -h --help"
+    opts=" -b --byte -l --line -c --character -w --word -a --all --color -n --no-ignore -N --no-extract-archive -@ --filelist -f --format -T --template --sort --top --subtotal --depth --stats --histogram --fail-fast --max-errors -o --output --no-header --quote-all --allow-private-urls --bind --cors-credentials --cors-headers --cors-max-age --cors-methods --cors-origins --job-timeout --job-ttl --max-body-size --max-expansion-size --max-jobs --max-requests --request-timeout -p --port --rate-limit --root -s --server --shutdown-timeout --store-dir --tls-cert --tls-key --tls-self-signed --token-file --unix-socket -h --help"
     if [[ "$cur" =~ ^\- ]]; then
         COMPREPLY=( $(compgen -W "${opts}" -- "${cur}") )
         return 0
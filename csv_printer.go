@@ -0,0 +1,164 @@
+package wildcat
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/tamada/wildcat/errors"
+)
+
+// CsvOptions represents the options for printing the results in the csv (or tsv) format.
+type CsvOptions struct {
+	// Delimiter is the field delimiter, such as ',' for csv and '\t' for tsv.
+	Delimiter rune
+	// NoHeader suppresses the header row.
+	NoHeader bool
+	// QuoteAll quotes all fields.  If false, the fields are quoted only if needed.
+	QuoteAll bool
+}
+
+// NewCsvPrinter generates the printer for printing results in the csv format by the given options.
+// Each row starts with the record type ("entry", "subtotal", "error", "total", "statistics", or "histogram"),
+// and the counts are printed in the raw numbers.
+// The "error" rows show the name and the message of the errors in counting, and have the same number of fields as the other rows.
+func NewCsvPrinter(dest io.Writer, opts *CsvOptions) Printer {
+	delimiter := opts.Delimiter
+	if delimiter == 0 {
+		delimiter = ','
+	}
+	writer := csv.NewWriter(dest)
+	writer.Comma = delimiter
+	return &csvPrinter{dest: dest, writer: writer, delimiter: delimiter, noHeader: opts.NoHeader, quoteAll: opts.QuoteAll}
+}
+
+type csvPrinter struct {
+	dest      io.Writer
+	writer    *csv.Writer
+	delimiter rune
+	noHeader  bool
+	quoteAll  bool
+	ct        CounterType
+	err       error
+}
+
+func (cp *csvPrinter) PrintHeader(ct CounterType) {
+	cp.ct = ct
+	if cp.noHeader {
+		return
+	}
+	record := []string{"type", "name"}
+	for index, label := range labels {
+		if ct.IsType(types[index]) {
+			record = append(record, label)
+		}
+	}
+	cp.write(record)
+}
+
+func (cp *csvPrinter) PrintEach(fileName string, counter Counter, index int) {
+	cp.PrintEntry(NewArg(fileName), counter, index)
+}
+
+func (cp *csvPrinter) PrintEntry(entry NameAndIndex, counter Counter, index int) {
+	recordType := "entry"
+	if IsSubtotal(entry) {
+		recordType = "subtotal"
+	}
+	cp.write(cp.record(recordType, entry.Name(), counter))
+}
+
+// PrintError prints the error in the row of the "error" type.
+// The message is placed at the first column of the counts, and the other columns are empty.
+func (cp *csvPrinter) PrintError(err *errors.Error, index int) {
+	record := []string{"error", err.Name}
+	for _, t := range types {
+		if cp.ct.IsType(t) {
+			record = append(record, "")
+		}
+	}
+	if len(record) > 2 {
+		record[2] = err.Error()
+	}
+	cp.write(record)
+}
+
+func (cp *csvPrinter) PrintTotal(rs *ResultSet) {
+	cp.write(cp.record("total", "total", rs.total))
+}
+
+// PrintStatistics prints the statistics in the rows of the "statistics" type, and the histogram in the rows of the "histogram" type.
+// The name of a histogram row is the range of the bin, and the number of entries is placed at the column of the histogram type.
+func (cp *csvPrinter) PrintStatistics(stats *Statistics) {
+	for index, label := range statisticsLabels {
+		record := []string{"statistics", label}
+		for _, t := range types {
+			if cp.ct.IsType(t) {
+				record = append(record, strconv.FormatInt(stats.Summaries[t].values()[index], 10))
+			}
+		}
+		cp.write(record)
+	}
+	if stats.Histogram != nil {
+		cp.printHistogram(stats.Histogram)
+	}
+}
+
+func (cp *csvPrinter) printHistogram(histogram *Histogram) {
+	for _, bin := range histogram.Bins {
+		record := []string{"histogram", bin.label(histogram.Type, &defaultSizer{})}
+		for _, t := range types {
+			switch {
+			case t == histogram.Type:
+				record = append(record, strconv.Itoa(bin.Count))
+			case cp.ct.IsType(t):
+				record = append(record, "")
+			}
+		}
+		cp.write(record)
+	}
+}
+
+func (cp *csvPrinter) PrintFooter() {
+	cp.writer.Flush()
+	if cp.err == nil {
+		cp.err = cp.writer.Error()
+	}
+}
+
+// Err returns the first error occurred in writing records.
+func (cp *csvPrinter) Err() error {
+	return cp.err
+}
+
+func (cp *csvPrinter) record(recordType, name string, counter Counter) []string {
+	record := []string{recordType, name}
+	for _, t := range types {
+		if cp.ct.IsType(t) {
+			record = append(record, strconv.FormatInt(counter.Count(t), 10))
+		}
+	}
+	return record
+}
+
+func (cp *csvPrinter) write(record []string) {
+	if cp.err != nil {
+		return
+	}
+	if cp.quoteAll {
+		cp.writer.Flush()
+		_, cp.err = io.WriteString(cp.dest, quoteAll(record, cp.delimiter))
+		return
+	}
+	cp.err = cp.writer.Write(record)
+}
+
+// quoteAll builds the row with quoting all fields, since encoding/csv quotes the fields only if needed.
+func quoteAll(record []string, delimiter rune) string {
+	fields := []string{}
+	for _, field := range record {
+		fields = append(fields, `"`+strings.ReplaceAll(field, `"`, `""`)+`"`)
+	}
+	return strings.Join(fields, string(delimiter)) + "\n"
+}
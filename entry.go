@@ -1,13 +1,17 @@
 package wildcat
 
 import (
+	"context"
+	stderrors "errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 
+	"github.com/tamada/wildcat/errors"
 	"github.com/tamada/wildcat/iowrapper"
 )
 
@@ -18,6 +22,21 @@ type Entry interface {
 	Open() (iowrapper.ReadCloseTypeParser, error)
 }
 
+// ContextEntry is implemented by the entries which stop counting when the given context is done.
+// The returned Either may have the partial results with the error of the context.
+type ContextEntry interface {
+	Entry
+	CountContext(ctx context.Context, generator Generator) *Either
+}
+
+// countEntry counts the given entry with the given context, if the entry implements ContextEntry.
+func countEntry(ctx context.Context, entry Entry, generator Generator) *Either {
+	if ce, ok := entry.(ContextEntry); ok {
+		return ce.CountContext(ctx, generator)
+	}
+	return entry.Count(generator)
+}
+
 // NameAndIndex means that the implemented object has the name and index.
 type NameAndIndex interface {
 	Name() string
@@ -26,6 +45,7 @@ type NameAndIndex interface {
 
 type CompressedEntry struct {
 	entry  Entry
+	limit  int64
 	reader iowrapper.ReadCloseTypeParser
 }
 
@@ -52,16 +72,23 @@ func (ce *CompressedEntry) Count(generator Generator) *Either {
 	return CountDefault(ce, generator())
 }
 
+// CountContext counts the receiver entry until the given context is done.
+func (ce *CompressedEntry) CountContext(ctx context.Context, generator Generator) *Either {
+	return CountDefaultContext(ctx, ce, generator())
+}
+
 func (ce *CompressedEntry) openImpl() (io.ReadCloser, error) {
 	reader, err := ce.entry.Open()
 	if err != nil {
 		return nil, err
 	}
-	return wrapReader(iowrapper.NewReader(reader)), nil
+	decompressed := wrapReader(iowrapper.NewReader(reader))
+	return &myReadCloser{reader: newExpansionLimiter(ce.limit).wrap(decompressed), closer: decompressed}, nil
 }
 
 type FileEntry struct {
 	nai    NameAndIndex
+	path   string
 	reader iowrapper.ReadCloseTypeParser
 }
 
@@ -70,7 +97,7 @@ func NewFileEntry(fileName string) *FileEntry {
 }
 
 func NewFileEntryWithIndex(nai NameAndIndex) *FileEntry {
-	return &FileEntry{nai: nai}
+	return &FileEntry{nai: nai, path: nai.Name()}
 }
 
 func (fe *FileEntry) Name() string {
@@ -85,9 +112,9 @@ func (fe *FileEntry) Open() (iowrapper.ReadCloseTypeParser, error) {
 	if fe.reader != nil {
 		return fe.reader, nil
 	}
-	reader, err := os.Open(fe.Name())
+	reader, err := os.Open(fe.path)
 	if err != nil {
-		return nil, err
+		return nil, renamePathError(err, fe.Name())
 	}
 	fe.reader = iowrapper.NewReader(reader)
 	return fe.reader, nil
@@ -97,9 +124,16 @@ func (fe *FileEntry) Count(generator Generator) *Either {
 	return CountDefault(fe, generator())
 }
 
+// CountContext counts the receiver entry until the given context is done.
+func (fe *FileEntry) CountContext(ctx context.Context, generator Generator) *Either {
+	return CountDefaultContext(ctx, fe, generator())
+}
+
 type URLEntry struct {
 	nai    NameAndIndex
 	reader iowrapper.ReadCloseTypeParser
+	ctx    context.Context
+	client *http.Client
 }
 
 func (ue *URLEntry) Name() string {
@@ -117,23 +151,54 @@ func (ue *URLEntry) Open() (iowrapper.ReadCloseTypeParser, error) {
 	return ue.openImpl()
 }
 
+func (ue *URLEntry) context() context.Context {
+	if ue.ctx == nil {
+		return context.Background()
+	}
+	return ue.ctx
+}
+
+func (ue *URLEntry) httpClient() *http.Client {
+	if ue.client == nil {
+		return http.DefaultClient
+	}
+	return ue.client
+}
+
 func (ue *URLEntry) openImpl() (iowrapper.ReadCloseTypeParser, error) {
-	response, err := http.Get(ue.Name())
+	request, err := http.NewRequestWithContext(ue.context(), http.MethodGet, ue.Name(), nil)
+	if err != nil {
+		return nil, ue.newError(errors.HTTP, fmt.Errorf("%s: http error: %w", ue.Name(), err))
+	}
+	response, err := ue.httpClient().Do(request)
 	if err != nil {
-		return nil, fmt.Errorf("%s: http error: %w", ue.Name(), err)
+		return nil, ue.newError(errors.HTTP, fmt.Errorf("%s: http error: %w", ue.Name(), err))
 	}
 	if response.StatusCode == 404 {
 		defer response.Body.Close()
-		return nil, fmt.Errorf("%s: file not found", ue.Name())
+		return nil, ue.newError(errors.NotFound, fmt.Errorf("%s: file not found", ue.Name()))
+	}
+	if response.StatusCode >= 400 {
+		defer response.Body.Close()
+		return nil, ue.newError(errors.HTTP, fmt.Errorf("%s: http error: %s", ue.Name(), response.Status))
 	}
 	ue.reader = iowrapper.NewReader(response.Body)
 	return ue.reader, nil
 }
 
+func (ue *URLEntry) newError(kind errors.Kind, err error) error {
+	return errors.NewError(kind, "get", ue.Name(), ue.Index().String(), err)
+}
+
 func (ue *URLEntry) Count(generator Generator) *Either {
 	return CountDefault(ue, generator())
 }
 
+// CountContext counts the receiver entry until the given context is done.
+func (ue *URLEntry) CountContext(ctx context.Context, generator Generator) *Either {
+	return CountDefaultContext(ctx, ue, generator())
+}
+
 type stdinEntry struct {
 	index  *Order
 	reader iowrapper.ReadCloseTypeParser
@@ -141,13 +206,25 @@ type stdinEntry struct {
 
 // CountDefault is the default routine for counting.
 func CountDefault(entry Entry, counter Counter) *Either {
+	return CountDefaultContext(context.Background(), entry, counter)
+}
+
+// CountDefaultContext is the default routine for counting, which stops reading the entry when the given context is done.
+func CountDefaultContext(ctx context.Context, entry Entry, counter Counter) *Either {
 	reader, err := entry.Open()
 	if err != nil {
 		return &Either{Err: err}
 	}
 	defer reader.Close()
-	if err := drainDataFromReader(reader, counter); err != nil {
-		return &Either{Err: err}
+	if err := drainDataFromReader(newContextReader(ctx, reader), counter); err != nil {
+		kind := errors.IO
+		switch {
+		case stderrors.Is(err, errExpansionExceeded):
+			kind = errors.Archive
+		case ctx.Err() != nil:
+			kind = errors.Canceled
+		}
+		return &Either{Err: errors.NewError(kind, "read", entry.Name(), entry.Index().String(), err)}
 	}
 	return &Either{Results: []*Result{newResult(entry, counter)}}
 }
@@ -160,6 +237,11 @@ func (se *stdinEntry) Count(generator Generator) *Either {
 	return CountDefault(se, generator())
 }
 
+// CountContext counts the receiver entry until the given context is done.
+func (se *stdinEntry) CountContext(ctx context.Context, generator Generator) *Either {
+	return CountDefaultContext(ctx, se, generator())
+}
+
 func (se *stdinEntry) Open() (iowrapper.ReadCloseTypeParser, error) {
 	if se.reader == nil {
 		se.reader = iowrapper.NewReader(os.Stdin)
@@ -173,6 +255,7 @@ func (se *stdinEntry) Index() *Order {
 
 type downloadURLEntry struct {
 	entry  *URLEntry
+	dir    string
 	reader iowrapper.ReadCloseTypeParser
 }
 
@@ -188,6 +271,11 @@ func (due *downloadURLEntry) Count(generator Generator) *Either {
 	return CountDefault(due, generator())
 }
 
+// CountContext counts the receiver entry until the given context is done.
+func (due *downloadURLEntry) CountContext(ctx context.Context, generator Generator) *Either {
+	return CountDefaultContext(ctx, due, generator())
+}
+
 func (due *downloadURLEntry) Open() (iowrapper.ReadCloseTypeParser, error) {
 	if due.reader != nil {
 		return due.reader, nil
@@ -196,28 +284,38 @@ func (due *downloadURLEntry) Open() (iowrapper.ReadCloseTypeParser, error) {
 	if err != nil {
 		return nil, err
 	}
-	in, err := createTeeReader(reader, due.Name())
+	in, err := createTeeReader(reader, due.Name(), due.dir)
+	if err != nil {
+		reader.Close()
+		return nil, err
+	}
 	due.reader = in
-	return in, err
+	return in, nil
 }
 
-func createTeeReader(reader io.ReadCloser, name string) (iowrapper.ReadCloseTypeParser, error) {
+// createTeeReader creates the reader storing the content into the file in the given directory named by the last element of the given url.
+// The existing files are not overwritten.
+func createTeeReader(reader io.ReadCloser, name, dir string) (iowrapper.ReadCloseTypeParser, error) {
 	u, err := url.Parse(name)
 	if err != nil {
 		return nil, fmt.Errorf("url.Parse failed: %w", err)
 	}
 	newName := path.Base(u.Path)
-	writer, err := os.Create(newName)
+	if newName == "." || newName == ".." || newName == "/" {
+		return nil, fmt.Errorf("%s: no file name to store the content", name)
+	}
+	writer, err := os.OpenFile(filepath.Join(dir, newName), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
 	if err != nil {
 		return nil, fmt.Errorf("%s: file creation error (%w)", newName, err)
 	}
 	return iowrapper.NewReader(iowrapper.NewTeeReader(reader, writer)), nil
 }
 
-func toURLEntry(arg NameAndIndex, opts *RuntimeOptions) Entry {
-	newEntry := &URLEntry{nai: arg}
+// toURLEntry creates the entry of the given url, the http request is cancelled when the given context is done.
+func toURLEntry(ctx context.Context, arg NameAndIndex, opts *RuntimeOptions) Entry {
+	newEntry := &URLEntry{nai: arg, ctx: ctx, client: opts.HTTPClient}
 	if opts.StoreContent {
-		return &downloadURLEntry{entry: newEntry}
+		return &downloadURLEntry{entry: newEntry, dir: opts.StoreDir}
 	}
 	return newEntry
 }
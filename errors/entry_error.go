@@ -0,0 +1,110 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Error is the error occurred in counting an entry, which carries the kind, the operation, the name and the order of the entry, and the cause.
+// The message of Error is the message of the cause.
+type Error struct {
+	Kind  Kind
+	Op    string
+	Name  string
+	Order string
+	Err   error
+}
+
+// The sentinel errors for each kind, errors.Is(err, ErrNotFound) reports whether err is an Error of NotFound.
+var (
+	ErrNotFound   = &Error{Kind: NotFound}
+	ErrPermission = &Error{Kind: Permission}
+	ErrHTTP       = &Error{Kind: HTTP}
+	ErrArchive    = &Error{Kind: Archive}
+	ErrIO         = &Error{Kind: IO}
+)
+
+// NewError creates an Error of the given kind.
+// If the given kind is Unknown, the kind is guessed from the given error by KindOf.
+func NewError(kind Kind, op, name, order string, err error) *Error {
+	if kind == Unknown {
+		kind = KindOf(err)
+	}
+	return &Error{Kind: kind, Op: op, Name: name, Order: order, Err: err}
+}
+
+// Wrap wraps the given error with the operation, the name and the order of the entry.
+// This function returns nil if the given error is nil, and returns the given error as it is
+// if it is a Center.  If the given error already is an Error, this function returns the copy of it with the empty fields filled,
+// since the given error may be shared by the other entries.
+func Wrap(op, name, order string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var entryErr *Error
+	var center *Center
+	switch {
+	case errors.As(err, &center):
+		return err
+	case errors.As(err, &entryErr):
+		if entryErr.Name != "" && entryErr.Op != "" {
+			return err
+		}
+		filled := *entryErr
+		if filled.Name == "" {
+			filled.Name, filled.Order = name, order
+		}
+		if filled.Op == "" {
+			filled.Op = op
+		}
+		if err != error(entryErr) {
+			filled.Err = err
+		}
+		return &filled
+	default:
+		return NewError(Unknown, op, name, order, err)
+	}
+}
+
+// ToError converts the given error to Error, the name and the order are empty if the given error is not an Error.
+func ToError(err error) *Error {
+	var entryErr *Error
+	if errors.As(err, &entryErr) {
+		return entryErr
+	}
+	return NewError(Unknown, "", "", "", err)
+}
+
+// Error returns the message of the cause.
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Kind.String()
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the cause.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the receiver matches the given target.
+// The target matches if it is an Error of the same kind, and its name is empty or the same.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	return t.Kind == e.Kind && (t.Name == "" || t.Name == e.Name)
+}
+
+// MarshalJSON serializes the receiver into json.
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Kind    Kind   `json:"kind"`
+		Op      string `json:"op,omitempty"`
+		Name    string `json:"name"`
+		Order   string `json:"order"`
+		Message string `json:"message"`
+	}{e.Kind, e.Op, e.Name, e.Order, e.Error()})
+}
@@ -0,0 +1,99 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	entryErr := &Error{Name: "file1", Order: "1", Err: fmt.Errorf("error1")}
+	center := New()
+	center.Push(fmt.Errorf("error2"))
+	testdata := []struct {
+		giveErr   error
+		wontName  string
+		wontOrder string
+		wontNil   bool
+	}{
+		{nil, "", "", true},
+		{os.ErrNotExist, "file2", "2", false},
+		{entryErr, "file1", "1", false},
+		{fmt.Errorf("wrapped: %w", entryErr), "file1", "1", false},
+		{&Error{Kind: NotFound, Err: os.ErrNotExist}, "file2", "2", false},
+		{fmt.Errorf("wrapped: %w", &Error{Kind: HTTP, Err: fmt.Errorf("error3")}), "file2", "2", false},
+	}
+	for _, td := range testdata {
+		got := Wrap("open", "file2", "2", td.giveErr)
+		if (got == nil) != td.wontNil {
+			t.Errorf("Wrap(%v) wont nil %v, got %v", td.giveErr, td.wontNil, got)
+		}
+		if got == nil {
+			continue
+		}
+		result := ToError(got)
+		if result.Name != td.wontName || result.Order != td.wontOrder {
+			t.Errorf("Wrap(%v) did not match, wont (%s, %s), got (%s, %s)", td.giveErr, td.wontName, td.wontOrder, result.Name, result.Order)
+		}
+		if !errors.Is(got, td.giveErr) {
+			t.Errorf("Wrap(%v) should wrap the given error", td.giveErr)
+		}
+	}
+	if Wrap("open", "file3", "3", center) != center {
+		t.Errorf("Wrap(center) should return the given center")
+	}
+}
+
+func TestWrapDoesNotModifyGivenError(t *testing.T) {
+	shared := &Error{Kind: HTTP, Err: fmt.Errorf("http error")}
+	err1 := ToError(Wrap("get", "url1", "1", shared))
+	err2 := ToError(Wrap("get", "url2", "2", fmt.Errorf("wrapped: %w", shared)))
+	if shared.Name != "" || shared.Order != "" || shared.Op != "" {
+		t.Errorf("Wrap modified the given error, got %v", shared)
+	}
+	if err1.Name != "url1" || err2.Name != "url2" || err1.Op != "get" {
+		t.Errorf("wrapped errors did not match, got %v and %v", err1, err2)
+	}
+	if err2.Error() != "wrapped: http error" {
+		t.Errorf("message of the wrapped error did not match, got %s", err2.Error())
+	}
+}
+
+func TestErrorIs(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", NewError(NotFound, "stat", "file1", "1", os.ErrNotExist))
+	testdata := []struct {
+		giveTarget error
+		wontResult bool
+	}{
+		{ErrNotFound, true},
+		{ErrHTTP, false},
+		{&Error{Kind: NotFound, Name: "file1"}, true},
+		{&Error{Kind: NotFound, Name: "file2"}, false},
+		{os.ErrNotExist, true},
+	}
+	for _, td := range testdata {
+		if got := errors.Is(err, td.giveTarget); got != td.wontResult {
+			t.Errorf("errors.Is(%v) did not match, wont %v, got %v", td.giveTarget, td.wontResult, got)
+		}
+	}
+	var entryErr *Error
+	if !errors.As(err, &entryErr) || entryErr.Op != "stat" {
+		t.Errorf("errors.As did not find Error, got %v", entryErr)
+	}
+}
+
+func TestErrorMarshalJSON(t *testing.T) {
+	center := New()
+	center.Push(NewError(HTTP, "get", "https://example.com/", "0", fmt.Errorf("https://example.com/: http error: 500 Internal Server Error")))
+	center.Push(fmt.Errorf("error2"))
+	data, err := json.Marshal(center)
+	if err != nil {
+		t.Errorf("json.Marshal failed: %v", err)
+	}
+	wont := `[{"kind":"http","op":"get","name":"https://example.com/","order":"0","message":"https://example.com/: http error: 500 Internal Server Error"},{"kind":"unknown","name":"","order":"","message":"error2"}]`
+	if string(data) != wont {
+		t.Errorf("json did not match, wont %s, got %s", wont, string(data))
+	}
+}
@@ -1,16 +1,24 @@
 package errors
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 )
 
 // Center collects errors.
-// This type can treat as error.
+// This type can treat as error, and is safe for concurrent use by multiple goroutines.
+// In the bounded mode (created by NewWithLimit), Center stops collecting errors after the max number of errors,
+// and notifies it through the channel of Exceeded for aborting the process.
 type Center struct {
-	errs []error
+	mutex    sync.Mutex
+	errs     []error
+	limit    int
+	dropped  int
+	exceeded chan struct{}
 }
 
 // New creates a new instance of ErrorCenter
@@ -18,28 +26,100 @@ func New() *Center {
 	return &Center{errs: []error{}}
 }
 
+// NewWithLimit creates a new instance of ErrorCenter in the bounded mode, which collects errors until the given max.
+// If the given max is zero or less, the created instance is the same as New.
+func NewWithLimit(max int) *Center {
+	if max <= 0 {
+		return New()
+	}
+	return &Center{errs: []error{}, limit: max, exceeded: make(chan struct{})}
+}
+
 // Size returns the size of errors.
 func (ec *Center) Size() int {
+	ec.mutex.Lock()
+	defer ec.mutex.Unlock()
 	return len(ec.errs)
 }
 
 // Push puts the given error into the receiver error center instance.
+// In the bounded mode, the errors after reaching the limit are dropped.
 func (ec *Center) Push(err error) bool {
 	if err == nil {
 		return false
 	}
 	var otherCenter *Center
 	if errors.As(err, &otherCenter) {
-		ec.errs = append(ec.errs, otherCenter.errs...)
+		if otherCenter != ec {
+			ec.append(otherCenter.Errors()...)
+		}
 	} else if err != io.EOF {
-		ec.errs = append(ec.errs, err)
+		ec.append(err)
 	}
 	return true
 }
 
+func (ec *Center) append(errs ...error) {
+	ec.mutex.Lock()
+	defer ec.mutex.Unlock()
+	for _, err := range errs {
+		if ec.isExceededImpl() {
+			ec.dropped++
+			continue
+		}
+		ec.errs = append(ec.errs, err)
+		if ec.isExceededImpl() {
+			close(ec.exceeded)
+		}
+	}
+}
+
+func (ec *Center) isExceededImpl() bool {
+	return ec.limit > 0 && len(ec.errs) >= ec.limit
+}
+
+// IsExceeded reports whether the receiver collected the max number of errors in the bounded mode.
+func (ec *Center) IsExceeded() bool {
+	ec.mutex.Lock()
+	defer ec.mutex.Unlock()
+	return ec.isExceededImpl()
+}
+
+// Exceeded returns the channel which is closed when the receiver collected the max number of errors.
+// The returned channel is never closed if the receiver is not in the bounded mode.
+func (ec *Center) Exceeded() <-chan struct{} {
+	return ec.exceeded
+}
+
+// Dropped returns the number of errors dropped after reaching the limit.
+func (ec *Center) Dropped() int {
+	ec.mutex.Lock()
+	defer ec.mutex.Unlock()
+	return ec.dropped
+}
+
+// Errors returns the copy of the errors in the receiver error center instance.
+func (ec *Center) Errors() []error {
+	ec.mutex.Lock()
+	defer ec.mutex.Unlock()
+	errs := make([]error, len(ec.errs))
+	copy(errs, ec.errs)
+	return errs
+}
+
+// Is reports whether any error in the receiver matches the given target by errors.Is.
+func (ec *Center) Is(target error) bool {
+	for _, err := range ec.Errors() {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
 // IsEmpty confirms the errors in the receiver error center instance is zero.
 func (ec *Center) IsEmpty() bool {
-	return len(ec.errs) == 0
+	return ec.Size() == 0
 }
 
 // Error returns the error messages in the receiver error center instance.
@@ -51,7 +131,44 @@ func (ec *Center) Error() string {
 
 // Println prints the error messages in the receiver error center instance to the given destination.
 func (ec *Center) Println(dest io.Writer) {
-	for _, err := range ec.errs {
+	for _, err := range ec.Errors() {
 		fmt.Fprintln(dest, err.Error())
 	}
 }
+
+// Filter returns the new Center which contains the errors of the given kinds in the receiver.
+func (ec *Center) Filter(kinds ...Kind) *Center {
+	result := New()
+	for _, err := range ec.Errors() {
+		kind := KindOf(err)
+		for _, k := range kinds {
+			if kind == k {
+				result.errs = append(result.errs, err)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// GroupByKind groups the errors in the receiver by their kinds.
+func (ec *Center) GroupByKind() map[Kind]*Center {
+	groups := map[Kind]*Center{}
+	for _, err := range ec.Errors() {
+		kind := KindOf(err)
+		if _, ok := groups[kind]; !ok {
+			groups[kind] = New()
+		}
+		groups[kind].errs = append(groups[kind].errs, err)
+	}
+	return groups
+}
+
+// MarshalJSON serializes the errors in the receiver into the json array of Error.
+func (ec *Center) MarshalJSON() ([]byte, error) {
+	errs := []*Error{}
+	for _, err := range ec.Errors() {
+		errs = append(errs, ToError(err))
+	}
+	return json.Marshal(errs)
+}
@@ -1,8 +1,11 @@
 package errors
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -46,3 +49,102 @@ error4` {
 		t.Errorf("ec1.Error() did not match, wont error1\nerror2\nerror3\nerror4, got %s", ec1.Error())
 	}
 }
+
+func TestFilterAndGroupByKind(t *testing.T) {
+	ec := New()
+	ec.Push(NewError(NotFound, "stat", "file1", "0", fmt.Errorf("file1: file or directory not found")))
+	ec.Push(NewError(HTTP, "get", "https://example.com/", "1", fmt.Errorf("https://example.com/: http error")))
+	ec.Push(NewError(NotFound, "stat", "file2", "2", fmt.Errorf("file2: file or directory not found")))
+	ec.Push(fmt.Errorf("error1"))
+
+	if got := ec.Filter(NotFound).Size(); got != 2 {
+		t.Errorf("Filter(NotFound) size did not match, wont 2, got %d", got)
+	}
+	if got := ec.Filter(HTTP, Unknown).Size(); got != 2 {
+		t.Errorf("Filter(HTTP, Unknown) size did not match, wont 2, got %d", got)
+	}
+	groups := ec.GroupByKind()
+	wonts := map[Kind]int{NotFound: 2, HTTP: 1, Unknown: 1}
+	if len(groups) != len(wonts) {
+		t.Errorf("GroupByKind size did not match, wont %d, got %d", len(wonts), len(groups))
+	}
+	for kind, size := range wonts {
+		if groups[kind] == nil || groups[kind].Size() != size {
+			t.Errorf("GroupByKind()[%s] did not match, wont %d, got %v", kind, size, groups[kind])
+		}
+	}
+}
+
+func TestConcurrentPush(t *testing.T) {
+	ec := New()
+	other := New()
+	other.Push(fmt.Errorf("other1"))
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			ec.Push(fmt.Errorf("error%d", index))
+			ec.Push(other)
+			ec.Size()
+			_ = ec.Error()
+		}(i)
+	}
+	wg.Wait()
+	if ec.Size() != 100 {
+		t.Errorf("ec.Size() did not match, wont 100, got %d", ec.Size())
+	}
+}
+
+func TestBoundedCenter(t *testing.T) {
+	testdata := []struct {
+		giveLimit    int
+		givePushes   int
+		wontSize     int
+		wontDropped  int
+		wontExceeded bool
+	}{
+		{0, 10, 10, 0, false},
+		{5, 3, 3, 0, false},
+		{5, 5, 5, 0, true},
+		{5, 50, 5, 45, true},
+	}
+	for _, td := range testdata {
+		ec := NewWithLimit(td.giveLimit)
+		var wg sync.WaitGroup
+		for i := 0; i < td.givePushes; i++ {
+			wg.Add(1)
+			go func(index int) {
+				defer wg.Done()
+				ec.Push(fmt.Errorf("error%d", index))
+			}(i)
+		}
+		wg.Wait()
+		if ec.Size() != td.wontSize || ec.Dropped() != td.wontDropped || ec.IsExceeded() != td.wontExceeded {
+			t.Errorf("NewWithLimit(%d) with %d errors did not match, wont (%d, %d, %v), got (%d, %d, %v)", td.giveLimit, td.givePushes,
+				td.wontSize, td.wontDropped, td.wontExceeded, ec.Size(), ec.Dropped(), ec.IsExceeded())
+		}
+		select {
+		case <-ec.Exceeded():
+			if !td.wontExceeded {
+				t.Errorf("NewWithLimit(%d) with %d errors: Exceeded should not be closed", td.giveLimit, td.givePushes)
+			}
+		default:
+			if td.wontExceeded {
+				t.Errorf("NewWithLimit(%d) with %d errors: Exceeded should be closed", td.giveLimit, td.givePushes)
+			}
+		}
+	}
+}
+
+func TestCenterIs(t *testing.T) {
+	ec := New()
+	ec.Push(NewError(NotFound, "stat", "file1", "0", fmt.Errorf("file1: file or directory not found")))
+	ec.Push(context.Canceled)
+	if !errors.Is(ec, context.Canceled) || !errors.Is(ec, ErrNotFound) {
+		t.Errorf("errors.Is should find the errors in the center")
+	}
+	if errors.Is(ec, ErrHTTP) || errors.Is(ec, context.DeadlineExceeded) {
+		t.Errorf("errors.Is should not find the errors which are not in the center")
+	}
+}
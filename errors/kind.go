@@ -0,0 +1,74 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Kind represents the kind of errors in counting entries.
+type Kind int
+
+const (
+	// Unknown is the kind of unclassified errors.
+	Unknown Kind = iota
+	// NotFound shows the entry (file, directory, or url) was not found.
+	NotFound
+	// Permission shows the entry could not be read by the permission.
+	Permission
+	// HTTP shows the url entry responded the error status, or the request failed.
+	HTTP
+	// Archive shows the archive file is corrupted or unsupported.
+	Archive
+	// IO shows the other errors in reading or writing data.
+	IO
+	// Canceled shows the counting was cancelled, or its deadline exceeded.
+	Canceled
+)
+
+var kindNames = []string{"unknown", "not-found", "permission", "http", "archive", "io", "canceled"}
+
+// String returns the name of the kind.
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return kindNames[Unknown]
+	}
+	return kindNames[k]
+}
+
+// MarshalText converts the kind to its name, for serializing Kind in json.
+func (k Kind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText parses the given name to the kind.
+func (k *Kind) UnmarshalText(text []byte) error {
+	for index, name := range kindNames {
+		if name == string(text) {
+			*k = Kind(index)
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: unknown error kind", string(text))
+}
+
+// KindOf returns the kind of the given error.
+// If the given error does not carry the kind, the kind is guessed from the wrapped errors (os.ErrNotExist, and os.ErrPermission).
+func KindOf(err error) Kind {
+	var entryErr *Error
+	switch {
+	case err == nil:
+		return Unknown
+	case errors.As(err, &entryErr) && entryErr.Kind != Unknown:
+		return entryErr.Kind
+	case errors.Is(err, os.ErrNotExist):
+		return NotFound
+	case errors.Is(err, os.ErrPermission):
+		return Permission
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		return Canceled
+	default:
+		return Unknown
+	}
+}
@@ -0,0 +1,42 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestKindOf(t *testing.T) {
+	testdata := []struct {
+		giveErr  error
+		wontKind Kind
+	}{
+		{nil, Unknown},
+		{fmt.Errorf("error1"), Unknown},
+		{&os.PathError{Op: "open", Path: "file1", Err: os.ErrNotExist}, NotFound},
+		{&os.PathError{Op: "open", Path: "file1", Err: os.ErrPermission}, Permission},
+		{NewError(Archive, "extract", "file1.zip", "0", fmt.Errorf("corrupted")), Archive},
+		{NewError(Unknown, "open", "file1", "0", os.ErrPermission), Permission},
+		{context.DeadlineExceeded, Canceled},
+	}
+	for _, td := range testdata {
+		if got := KindOf(td.giveErr); got != td.wontKind {
+			t.Errorf("KindOf(%v) did not match, wont %s, got %s", td.giveErr, td.wontKind, got)
+		}
+	}
+}
+
+func TestKindText(t *testing.T) {
+	for _, kind := range []Kind{Unknown, NotFound, Permission, HTTP, Archive, IO, Canceled} {
+		text, _ := kind.MarshalText()
+		var got Kind
+		if err := got.UnmarshalText(text); err != nil || got != kind {
+			t.Errorf("%s: UnmarshalText did not match, got %s (%v)", kind, got, err)
+		}
+	}
+	var kind Kind
+	if err := kind.UnmarshalText([]byte("timeout")); err == nil {
+		t.Errorf("UnmarshalText(timeout) should be error")
+	}
+}
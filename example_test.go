@@ -0,0 +1,33 @@
+package wildcat_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/tamada/wildcat"
+)
+
+func ExampleNew() {
+	wc := wildcat.New(wildcat.WithCounterType(wildcat.Lines|wildcat.Words), wildcat.WithThreads(4))
+	rs, err := wc.Count(context.Background(), "testdata/wc/humpty_dumpty.txt", "testdata/wc/ja/sakura_sakura.txt")
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+	rs.Print(wildcat.NewPrinter(os.Stdout, "csv", wildcat.BuildSizer(false)))
+	// Output:
+	// type,name,lines,words
+	// entry,testdata/wc/humpty_dumpty.txt,4,26
+	// entry,testdata/wc/ja/sakura_sakura.txt,15,26
+	// total,total,19,52
+}
+
+func ExampleWildcat_Count() {
+	wc := wildcat.New()
+	rs, err := wc.Count(context.Background(), "testdata/wc/humpty_dumpty.txt", "not_exist.txt")
+	fmt.Printf("%d result(s)\n", rs.Size())
+	fmt.Println(err.Error())
+	// Output:
+	// 1 result(s)
+	// not_exist.txt: file or directory not found
+}
@@ -9,5 +9,4 @@ require (
 	github.com/sabhiram/go-gitignore v0.0.0-20201211210132-54b8a0bf510f
 	github.com/spf13/pflag v1.0.5
 	github.com/vbauerster/mpb/v6 v6.0.3
-	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c
 )
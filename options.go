@@ -0,0 +1,121 @@
+package wildcat
+
+import (
+	"context"
+)
+
+// Option is the functional option for New.
+type Option func(*settings)
+
+type settings struct {
+	readOpts    ReadOptions
+	runtimeOpts RuntimeOptions
+	generator   Generator
+}
+
+// New creates an instance of Wildcat with the given options.
+// By default, the created Wildcat counts bytes, characters, words, and lines of the targets
+// with runtime.GOMAXPROCS workers, respecting .gitignore, and extracting archives.
+// The created Wildcat is reusable, and safe for concurrent use by multiple goroutines.
+func New(opts ...Option) *Wildcat {
+	s := &settings{generator: DefaultGenerator}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return NewWildcat(&s.readOpts, &s.runtimeOpts, s.generator)
+}
+
+// WithCounterType sets the counter types for counting (e.g., Lines | Words).
+func WithCounterType(ct CounterType) Option {
+	return func(s *settings) {
+		s.generator = func() Counter { return NewCounter(ct) }
+	}
+}
+
+// WithGenerator sets the generator of Counter for counting.
+func WithGenerator(generator Generator) Option {
+	return func(s *settings) {
+		s.generator = generator
+	}
+}
+
+// WithFileList treats the contents of targets as the file lists.
+func WithFileList() Option {
+	return func(s *settings) {
+		s.readOpts.FileList = true
+	}
+}
+
+// WithNoIgnore does not respect the ignore files (.gitignore).
+func WithNoIgnore() Option {
+	return func(s *settings) {
+		s.readOpts.NoIgnore = true
+	}
+}
+
+// WithNoExtract does not extract archive files, and treats them as the single binary files.
+func WithNoExtract() Option {
+	return func(s *settings) {
+		s.readOpts.NoExtract = true
+	}
+}
+
+// WithAllFiles reads the hidden files.
+func WithAllFiles() Option {
+	return func(s *settings) {
+		s.readOpts.AllFiles = true
+	}
+}
+
+// WithThreads sets the number of worker threads, zero or less means runtime.GOMAXPROCS.
+func WithThreads(number int) Option {
+	return func(s *settings) {
+		s.runtimeOpts.ThreadNumber = int64(number)
+	}
+}
+
+// WithProgress shows the progress bar while counting.
+func WithProgress() Option {
+	return func(s *settings) {
+		s.runtimeOpts.ShowProgress = true
+	}
+}
+
+// WithStoreContent stores the contents of url targets into the current directory.
+func WithStoreContent() Option {
+	return func(s *settings) {
+		s.runtimeOpts.StoreContent = true
+	}
+}
+
+// WithMaxErrors cancels the counting after the given number of errors, zero or less means no limit.
+func WithMaxErrors(max int) Option {
+	return func(s *settings) {
+		s.runtimeOpts.MaxErrors = max
+	}
+}
+
+// WithMaxExpansionSize limits the bytes expanded from each archive or compressed file, zero or less means no limit.
+func WithMaxExpansionSize(size int64) Option {
+	return func(s *settings) {
+		s.readOpts.MaxExpansionSize = size
+	}
+}
+
+// WithFailFast cancels the counting on the first error.
+func WithFailFast() Option {
+	return func(s *settings) {
+		s.runtimeOpts.FailFast = true
+	}
+}
+
+// Count counts the given targets (files, directories, and urls) until the given context is done.
+// If no targets are given, Count reads the standard input.
+// The returned error is nil if no errors occurred, otherwise, it is *errors.Center with the results counted successfully.
+func (wc *Wildcat) Count(ctx context.Context, targets ...string) (*ResultSet, error) {
+	rs, ec := wc.CountAllContext(ctx, NewArgf(targets, wc.config.readOpts, wc.config.runtimeOpts))
+	if ec.IsEmpty() {
+		return rs, nil
+	}
+	return rs, ec
+}
@@ -0,0 +1,49 @@
+package wildcat
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	wc := New(WithFileList(), WithNoIgnore(), WithNoExtract(), WithAllFiles(), WithThreads(2),
+		WithStoreContent(), WithMaxErrors(3), WithFailFast(), WithCounterType(Lines))
+	read, runtime := wc.config.readOpts, wc.config.runtimeOpts
+	if !read.FileList || !read.NoIgnore || !read.NoExtract || !read.AllFiles {
+		t.Errorf("read options did not match, got %v", read)
+	}
+	if runtime.ThreadNumber != 2 || !runtime.StoreContent || runtime.MaxErrors != 3 || !runtime.FailFast {
+		t.Errorf("runtime options did not match, got %v", runtime)
+	}
+	if ct := wc.generator().Type(); ct != Lines {
+		t.Errorf("counter type did not match, wont %v, got %v", Lines, ct)
+	}
+}
+
+func TestConcurrentCount(t *testing.T) {
+	testdata := []struct {
+		giveTargets    []string
+		wontResultSize int
+		wontError      bool
+	}{
+		{[]string{"testdata/wc"}, 3, false},
+		{[]string{"testdata/wc/humpty_dumpty.txt", "not_exist.txt"}, 1, true},
+		{[]string{"testdata/archives/wc.zip"}, 4, false},
+	}
+	wc := New(WithThreads(1))
+	group := new(sync.WaitGroup)
+	for i := 0; i < 5; i++ {
+		for _, td := range testdata {
+			group.Add(1)
+			go func(targets []string, wontSize int, wontError bool) {
+				defer group.Done()
+				rs, err := wc.Count(context.Background(), targets...)
+				if rs.Size() != wontSize || (err != nil) != wontError {
+					t.Errorf("Count(%v) did not match, wont %d results (error: %v), got %d results (%v)", targets, wontSize, wontError, rs.Size(), err)
+				}
+			}(td.giveTargets, td.wontResultSize, td.wontError)
+		}
+	}
+	group.Wait()
+}
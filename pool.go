@@ -0,0 +1,59 @@
+package wildcat
+
+import (
+	"runtime"
+)
+
+// queueSizePerWorker is the capacity of the work queue for each worker.
+const queueSizePerWorker = 64
+
+// workerPool runs the submitted tasks by the fixed number of workers through the bounded work queue.
+// If the queue is full, the producers block until the workers take the pending tasks (back pressure),
+// and the workers run the tasks by themselves, since the tasks for directories and file lists submit the tasks of their entries,
+// and the workers blocking on the full queue would deadlock.  Therefore, the tasks never run more than the workers concurrently.
+type workerPool struct {
+	tasks chan func()
+}
+
+// workerSize returns the number of workers by the given thread number, zero or less means runtime.GOMAXPROCS.
+func workerSize(threadNumber int64) int {
+	if threadNumber <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return int(threadNumber)
+}
+
+// newWorkerPool creates an instance of workerPool, and starts the given number of workers.
+func newWorkerPool(size int) *workerPool {
+	pool := &workerPool{tasks: make(chan func(), size*queueSizePerWorker)}
+	for i := 0; i < size; i++ {
+		go pool.work()
+	}
+	return pool
+}
+
+// submit pushes the given task into the work queue, and blocks while the queue is full.
+// This method must not be called from the workers, use submitFromWorker instead.
+func (wp *workerPool) submit(task func()) {
+	wp.tasks <- task
+}
+
+// submitFromWorker pushes the given task into the work queue, or runs it in the calling worker if the queue is full.
+func (wp *workerPool) submitFromWorker(task func()) {
+	select {
+	case wp.tasks <- task:
+	default:
+		task()
+	}
+}
+
+// close stops the workers after running all of pending tasks.  The tasks must not be submitted after closing.
+func (wp *workerPool) close() {
+	close(wp.tasks)
+}
+
+func (wp *workerPool) work() {
+	for task := range wp.tasks {
+		task()
+	}
+}
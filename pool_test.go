@@ -0,0 +1,154 @@
+package wildcat
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerSize(t *testing.T) {
+	testdata := []struct {
+		giveThreads int64
+		wontSize    int
+	}{
+		{1, 1},
+		{10, 10},
+		{0, runtime.GOMAXPROCS(0)},
+		{-1, runtime.GOMAXPROCS(0)},
+	}
+	for _, td := range testdata {
+		if got := workerSize(td.giveThreads); got != td.wontSize {
+			t.Errorf("workerSize(%d) did not match, wont %d, got %d", td.giveThreads, td.wontSize, got)
+		}
+	}
+}
+
+func TestWorkerPoolNestedTasks(t *testing.T) {
+	testdata := []struct {
+		giveSize  int
+		giveDepth int
+		giveWidth int
+		wontTasks int64
+	}{
+		{1, 3, 10, 1 + 10 + 100 + 1000},
+		{4, 3, 10, 1 + 10 + 100 + 1000},
+		{1, 1, 10000, 1 + 10000},
+	}
+	for _, td := range testdata {
+		pool := newWorkerPool(td.giveSize)
+		group := new(sync.WaitGroup)
+		var count int64
+		var task func(depth int)
+		task = func(depth int) {
+			defer group.Done()
+			atomic.AddInt64(&count, 1)
+			if depth == 0 {
+				return
+			}
+			for i := 0; i < td.giveWidth; i++ {
+				group.Add(1)
+				pool.submitFromWorker(func() { task(depth - 1) })
+			}
+		}
+		group.Add(1)
+		pool.submit(func() { task(td.giveDepth) })
+		group.Wait()
+		pool.close()
+		if count != td.wontTasks {
+			t.Errorf("pool(%d) ran tasks did not match, wont %d, got %d", td.giveSize, td.wontTasks, count)
+		}
+	}
+}
+
+func TestWorkerPoolBoundedQueue(t *testing.T) {
+	pool := newWorkerPool(1)
+	block := make(chan struct{})
+	started := make(chan struct{})
+	group := new(sync.WaitGroup)
+	group.Add(1)
+	pool.submit(func() {
+		defer group.Done()
+		close(started)
+		<-block
+	})
+	<-started
+	var count int64
+	capacity := cap(pool.tasks)
+	for i := 0; i < capacity; i++ {
+		group.Add(1)
+		pool.submit(func() {
+			defer group.Done()
+			atomic.AddInt64(&count, 1)
+		})
+	}
+	submitted := make(chan struct{})
+	group.Add(1)
+	go func() {
+		pool.submit(func() {
+			defer group.Done()
+			atomic.AddInt64(&count, 1)
+		})
+		close(submitted)
+	}()
+	select {
+	case <-submitted:
+		t.Errorf("submit should block while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if len(pool.tasks) != capacity || atomic.LoadInt64(&count) != 0 {
+		t.Errorf("queue should be bounded by %d, got %d queued, and %d ran by the submitter", capacity, len(pool.tasks), count)
+	}
+	close(block)
+	<-submitted
+	group.Wait()
+	pool.close()
+	if count != int64(capacity+1) {
+		t.Errorf("ran tasks did not match, wont %d, got %d", capacity+1, count)
+	}
+}
+
+func TestWorkerPoolConcurrency(t *testing.T) {
+	testdata := []struct {
+		giveSize  int
+		giveTasks int
+	}{
+		{1, 1000},
+		{4, 1000},
+	}
+	for _, td := range testdata {
+		pool := newWorkerPool(td.giveSize)
+		group := new(sync.WaitGroup)
+		var running, maxRunning int64
+		count := func() {
+			defer group.Done()
+			current := atomic.AddInt64(&running, 1)
+			for {
+				max := atomic.LoadInt64(&maxRunning)
+				if current <= max || atomic.CompareAndSwapInt64(&maxRunning, max, current) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Microsecond)
+			atomic.AddInt64(&running, -1)
+		}
+		group.Add(1)
+		pool.submit(func() {
+			defer group.Done()
+			for i := 0; i < td.giveTasks; i++ {
+				group.Add(1)
+				pool.submitFromWorker(count)
+			}
+		})
+		for i := 0; i < td.giveTasks; i++ {
+			group.Add(1)
+			pool.submit(count)
+		}
+		group.Wait()
+		pool.close()
+		if maxRunning > int64(td.giveSize) {
+			t.Errorf("pool(%d) ran %d tasks concurrently", td.giveSize, maxRunning)
+		}
+	}
+}
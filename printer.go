@@ -1,12 +1,14 @@
 package wildcat
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"strings"
 	"time"
 
 	"github.com/dustin/go-humanize"
+	"github.com/tamada/wildcat/errors"
 )
 
 var labels = []string{"lines", "words", "characters", "bytes"}
@@ -57,95 +59,143 @@ type Printer interface {
 	PrintFooter()
 }
 
+// StatisticsPrinter is implemented by the printers which print the statistics summary.
+// PrintStatistics is called between PrintTotal and PrintFooter only if the statistics summary is enabled.
+type StatisticsPrinter interface {
+	PrintStatistics(stats *Statistics)
+}
+
+// EntryPrinter is implemented by the printers which print the results with their entries,
+// e.g., for showing the orders of the entries, and distinguishing the subtotals from the files.
+// If the printer implements EntryPrinter, PrintEntry is called instead of PrintEach.
+type EntryPrinter interface {
+	PrintEntry(entry NameAndIndex, counter Counter, index int)
+}
+
+// printEach prints the given entry by PrintEntry if the printer implements EntryPrinter, otherwise, by PrintEach.
+func printEach(printer Printer, entry NameAndIndex, counter Counter, index int) {
+	if ep, ok := printer.(EntryPrinter); ok {
+		ep.PrintEntry(entry, counter, index)
+		return
+	}
+	printer.PrintEach(entry.Name(), counter, index)
+}
+
+// ErrorPrinter is implemented by the printers which print the errors in counting as the records of the results.
+// The error records are printed after the results of entries, and the index continues from them.
+type ErrorPrinter interface {
+	PrintError(err *errors.Error, index int)
+}
+
 // NewPrinter generates the suitable printer specified by given printerType to given dest.
-// Available printerType are: "json", "xml", "csv", and "default" (case insensitive).
+// Available printerType are: "json", "ndjson", "xml", "csv", "tsv", "markdown", "html", "latex", "tree", and "default" (case insensitive).
+// Note that "csv" and "tsv" printers print the raw numbers regardless of the given sizer.
 // If unknown type was given, the DefaultPrinter is returned.
 func NewPrinter(dest io.Writer, printerType string, sizer Sizer) Printer {
 	switch strings.ToLower(printerType) {
 	case "json":
 		return &jsonPrinter{dest: dest, sizer: sizer}
+	case "ndjson":
+		return &ndjsonPrinter{dest: dest, sizer: sizer}
 	case "xml":
 		return &xmlPrinter{dest: dest, sizer: sizer}
 	case "csv":
-		return &csvPrinter{dest: dest, sizer: sizer}
+		return NewCsvPrinter(dest, &CsvOptions{Delimiter: ','})
+	case "tsv":
+		return NewCsvPrinter(dest, &CsvOptions{Delimiter: '\t'})
+	case "markdown":
+		return &markdownPrinter{dest: dest, sizer: sizer}
+	case "html":
+		return &htmlPrinter{dest: dest, sizer: sizer}
+	case "latex":
+		return &latexPrinter{dest: dest, sizer: sizer}
+	case "tree":
+		return &treePrinter{dest: dest, sizer: sizer}
 	default:
-		return &defaultPrinter{dest: dest, sizer: sizer}
+		return NewDefaultPrinter(dest, sizer, false)
 	}
 }
 
+// NewDefaultPrinter generates the printer for printing results in the format like wc.
+// The widths of columns are computed from the results, and
+// the header and the total are decorated by ANSI escape sequences if color is true.
+func NewDefaultPrinter(dest io.Writer, sizer Sizer, color bool) Printer {
+	return &defaultPrinter{dest: dest, sizer: sizer, color: color}
+}
+
 type defaultPrinter struct {
-	dest  io.Writer
-	sizer Sizer
+	dest      io.Writer
+	sizer     Sizer
+	color     bool
+	ct        CounterType
+	table     *tableRows
+	subtotals map[int]bool
+	hasTotal  bool
+	stats     *Statistics
 }
 
 func (dp *defaultPrinter) PrintHeader(ct CounterType) {
-	for index, label := range labels {
-		if ct.IsType(types[index]) {
-			fmt.Fprintf(dp.dest, " %10s", label)
-		}
-	}
-	fmt.Fprintln(dp.dest)
+	dp.ct = ct
+	dp.table = &tableRows{}
+	dp.table.append(headerCells(ct))
+	dp.subtotals = map[int]bool{}
 }
 
 func (dp *defaultPrinter) PrintEach(fileName string, counter Counter, index int) {
-	for _, t := range types {
-		if counter.IsType(t) {
-			fmt.Fprintf(dp.dest, " %10s", dp.sizer.Convert(counter.Count(t), t))
-		}
-	}
-	fmt.Fprintf(dp.dest, " %s\n", fileName)
+	dp.PrintEntry(NewArg(fileName), counter, index)
 }
 
-func (dp *defaultPrinter) PrintTotal(rs *ResultSet) {
-	ct := rs.CounterType()
-	for _, t := range types {
-		if ct.IsType(t) {
-			fmt.Fprintf(dp.dest, " %10s", dp.sizer.Convert(rs.total.Count(t), t))
-		}
+func (dp *defaultPrinter) PrintEntry(entry NameAndIndex, counter Counter, index int) {
+	if IsSubtotal(entry) {
+		dp.subtotals[len(dp.table.rows)] = true
 	}
-	fmt.Fprintf(dp.dest, " %s\n", rs.total.Name())
+	dp.table.append(countCells(entry.Name(), counter, dp.ct, dp.sizer))
 }
 
-func (dp *defaultPrinter) PrintFooter() {
-	// do nothing.
-}
-
-type csvPrinter struct {
-	dest  io.Writer
-	sizer Sizer
+func (dp *defaultPrinter) PrintTotal(rs *ResultSet) {
+	dp.hasTotal = true
+	dp.table.append(countCells(rs.total.Name(), rs.total, dp.ct, dp.sizer))
 }
 
-func (cp *csvPrinter) PrintHeader(ct CounterType) {
-	fmt.Fprint(cp.dest, "file name")
-	for index, label := range labels {
-		if ct.IsType(types[index]) {
-			fmt.Fprintf(cp.dest, ",%s", label)
+func (dp *defaultPrinter) PrintFooter() {
+	widths := dp.table.widths()
+	for index, row := range dp.table.rows {
+		switch {
+		case index == 0:
+			dp.printRow(row[1:], "", widths, headerStyle)
+		case index == len(dp.table.rows)-1 && dp.hasTotal:
+			dp.printRow(row[1:], row[0], widths, totalStyle)
+		case dp.subtotals[index]:
+			dp.printRow(row[1:], row[0], widths, subtotalStyle)
+		default:
+			dp.printRow(row[1:], row[0], widths, "")
 		}
 	}
-	fmt.Fprintln(cp.dest)
-}
-
-func (cp *csvPrinter) PrintEach(fileName string, counter Counter, index int) {
-	fmt.Fprint(cp.dest, fileName)
-	for _, t := range types {
-		if counter.IsType(t) {
-			fmt.Fprintf(cp.dest, ",\"%s\"", cp.sizer.Convert(counter.Count(t), t))
-		}
+	if dp.stats != nil {
+		printPlainStatistics(dp.dest, dp.stats, dp.sizer, dp.color)
 	}
-	fmt.Fprintln(cp.dest)
 }
 
-func (cp *csvPrinter) PrintTotal(rs *ResultSet) {
-	cp.PrintEach("total", rs.total, 1)
+func (dp *defaultPrinter) PrintStatistics(stats *Statistics) {
+	dp.stats = stats
 }
 
-func (cp *csvPrinter) PrintFooter() {
-	// do nothing.
+// printRow prints the given counts in the right-aligned columns, and the given name in the last column.
+func (dp *defaultPrinter) printRow(counts []string, name string, widths []int, style string) {
+	line := new(strings.Builder)
+	for i, count := range counts {
+		fmt.Fprintf(line, " %s", padLeft(count, widths[i+1]))
+	}
+	if name != "" {
+		fmt.Fprintf(line, " %s", name)
+	}
+	fmt.Fprintln(dp.dest, decorate(line.String(), style, dp.color))
 }
 
 type xmlPrinter struct {
 	dest  io.Writer
 	sizer Sizer
+	stats *Statistics
 }
 
 func (xp *xmlPrinter) PrintHeader(ct CounterType) {
@@ -161,7 +211,28 @@ func escapeXML(from string) string {
 }
 
 func (xp *xmlPrinter) PrintEach(fileName string, counter Counter, index int) {
-	fmt.Fprintf(xp.dest, "<result><file-name>%s</file-name>", escapeXML(fileName))
+	xp.PrintEntry(NewArg(fileName), counter, index)
+}
+
+func (xp *xmlPrinter) PrintEntry(entry NameAndIndex, counter Counter, index int) {
+	xp.printEach(entry.Name(), counter, index, recordKind(entry))
+}
+
+// recordKind returns the kind of the given entry for the structured printers, the empty string means the plain entry.
+func recordKind(entry NameAndIndex) string {
+	if IsSubtotal(entry) {
+		return "subtotal"
+	}
+	return ""
+}
+
+func (xp *xmlPrinter) printEach(fileName string, counter Counter, index int, kind string) {
+	if kind != "" {
+		fmt.Fprintf(xp.dest, `<result type="%s">`, kind)
+	} else {
+		fmt.Fprint(xp.dest, "<result>")
+	}
+	fmt.Fprintf(xp.dest, "<file-name>%s</file-name>", escapeXML(fileName))
 	for index, label := range labels {
 		if counter.IsType(types[index]) {
 			fmt.Fprintf(xp.dest, "<%s>%s</%s>", label, xp.sizer.Convert(counter.Count(types[index]), types[index]), label)
@@ -170,17 +241,54 @@ func (xp *xmlPrinter) PrintEach(fileName string, counter Counter, index int) {
 	fmt.Fprintf(xp.dest, "</result>")
 }
 
+func (xp *xmlPrinter) PrintError(err *errors.Error, index int) {
+	fmt.Fprintf(xp.dest, `<error kind="%s">`, err.Kind)
+	fmt.Fprintf(xp.dest, "<file-name>%s</file-name><order>%s</order><message>%s</message></error>", escapeXML(err.Name), err.Order, escapeXML(err.Error()))
+}
+
 func (xp *xmlPrinter) PrintTotal(rs *ResultSet) {
-	xp.PrintEach("total", rs.total, 1)
+	xp.printEach("total", rs.total, 1, "")
+}
+
+func (xp *xmlPrinter) PrintStatistics(stats *Statistics) {
+	xp.stats = stats
 }
 
 func (xp *xmlPrinter) PrintFooter() {
-	fmt.Fprintln(xp.dest, "</results></wildcat>")
+	fmt.Fprint(xp.dest, "</results>")
+	if xp.stats != nil {
+		xp.printStatistics(xp.stats)
+	}
+	fmt.Fprintln(xp.dest, "</wildcat>")
+}
+
+func (xp *xmlPrinter) printStatistics(stats *Statistics) {
+	fmt.Fprintf(xp.dest, `<statistics entries="%d">`, stats.EntryCount)
+	for index, label := range labels {
+		summary, ok := stats.Summaries[types[index]]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(xp.dest, "<%s>", label)
+		for i, value := range summary.values() {
+			fmt.Fprintf(xp.dest, "<%s>%s</%s>", statisticsLabels[i], xp.sizer.Convert(value, types[index]), statisticsLabels[i])
+		}
+		fmt.Fprintf(xp.dest, "</%s>", label)
+	}
+	if histogram := stats.Histogram; histogram != nil {
+		fmt.Fprintf(xp.dest, `<histogram type="%s">`, labelOf(histogram.Type))
+		for _, bin := range histogram.Bins {
+			fmt.Fprintf(xp.dest, `<bin from="%s" to="%s" entries="%d"/>`, xp.sizer.Convert(bin.Lower, histogram.Type), xp.sizer.Convert(bin.Upper, histogram.Type), bin.Count)
+		}
+		fmt.Fprint(xp.dest, "</histogram>")
+	}
+	fmt.Fprint(xp.dest, "</statistics>")
 }
 
 type jsonPrinter struct {
 	dest  io.Writer
 	sizer Sizer
+	stats *Statistics
 }
 
 func now() string {
@@ -192,22 +300,136 @@ func (jp *jsonPrinter) PrintHeader(ct CounterType) {
 }
 
 func (jp *jsonPrinter) PrintEach(fileName string, counter Counter, index int) {
+	jp.PrintEntry(NewArg(fileName), counter, index)
+}
+
+func (jp *jsonPrinter) PrintEntry(entry NameAndIndex, counter Counter, index int) {
+	jp.printEach(entry.Name(), counter, index, recordKind(entry))
+}
+
+func (jp *jsonPrinter) printEach(fileName string, counter Counter, index int, kind string) {
 	if index != 0 {
 		fmt.Fprint(jp.dest, ",")
 	}
-	fmt.Fprintf(jp.dest, `{"filename":"%s"`, fileName)
+	writeJSONRecord(jp.dest, fileName, counter, kind, jp.sizer)
+}
+
+// writeJSONRecord writes the json object of the given counter, kind is the value of "type" field, and the empty kind omits it.
+func writeJSONRecord(dest io.Writer, fileName string, counter Counter, kind string, sizer Sizer) {
+	fmt.Fprintf(dest, `{"filename":"%s"`, fileName)
+	if kind != "" {
+		fmt.Fprintf(dest, `,"type":"%s"`, kind)
+	}
 	for i, ct := range types {
 		if counter.IsType(ct) {
-			fmt.Fprintf(jp.dest, `,"%s":"%s"`, labels[i], jp.sizer.Convert(counter.Count(ct), ct))
+			fmt.Fprintf(dest, `,"%s":"%s"`, labels[i], sizer.Convert(counter.Count(ct), ct))
 		}
 	}
-	fmt.Fprintf(jp.dest, `}`)
+	fmt.Fprintf(dest, `}`)
+}
+
+func (jp *jsonPrinter) PrintError(err *errors.Error, index int) {
+	if index != 0 {
+		fmt.Fprint(jp.dest, ",")
+	}
+	writeJSONError(jp.dest, err)
+}
+
+func writeJSONError(dest io.Writer, err *errors.Error) {
+	fmt.Fprintf(dest, `{"filename":%s,"type":"error","kind":"%s","order":"%s","message":%s}`, jsonString(err.Name), err.Kind, err.Order, jsonString(err.Error()))
+}
+
+func jsonString(str string) string {
+	data, _ := json.Marshal(str)
+	return string(data)
 }
 
 func (jp *jsonPrinter) PrintTotal(rs *ResultSet) {
-	jp.PrintEach("total", rs.total, 1)
+	jp.printEach("total", rs.total, 1, "")
+}
+
+func (jp *jsonPrinter) PrintStatistics(stats *Statistics) {
+	jp.stats = stats
 }
 
 func (jp *jsonPrinter) PrintFooter() {
-	fmt.Fprintln(jp.dest, `]}`)
+	fmt.Fprint(jp.dest, `]`)
+	if jp.stats != nil {
+		jp.printStatistics(jp.stats)
+	}
+	fmt.Fprintln(jp.dest, `}`)
+}
+
+func (jp *jsonPrinter) printStatistics(stats *Statistics) {
+	fmt.Fprint(jp.dest, `,"statistics":{`)
+	writeJSONStatistics(jp.dest, stats, jp.sizer)
+	fmt.Fprint(jp.dest, `}`)
+}
+
+// writeJSONStatistics writes the fields of the given statistics without the enclosing braces.
+func writeJSONStatistics(dest io.Writer, stats *Statistics, sizer Sizer) {
+	fmt.Fprintf(dest, `"entries":%d`, stats.EntryCount)
+	for index, label := range labels {
+		summary, ok := stats.Summaries[types[index]]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(dest, `,"%s":{`, label)
+		for i, value := range summary.values() {
+			if i != 0 {
+				fmt.Fprint(dest, ",")
+			}
+			fmt.Fprintf(dest, `"%s":"%s"`, statisticsLabels[i], sizer.Convert(value, types[index]))
+		}
+		fmt.Fprint(dest, `}`)
+	}
+	if histogram := stats.Histogram; histogram != nil {
+		fmt.Fprintf(dest, `,"histogram":{"type":"%s","bins":[`, labelOf(histogram.Type))
+		for i, bin := range histogram.Bins {
+			if i != 0 {
+				fmt.Fprint(dest, ",")
+			}
+			fmt.Fprintf(dest, `{"from":"%s","to":"%s","entries":%d}`, sizer.Convert(bin.Lower, histogram.Type), sizer.Convert(bin.Upper, histogram.Type), bin.Count)
+		}
+		fmt.Fprint(dest, `]}`)
+	}
+}
+
+// ndjsonPrinter prints each result as a json object in a line (newline delimited json), in the same schema as the records of jsonPrinter.
+// The errors and the statistics are also printed as the lines distinguished by "type" field.
+type ndjsonPrinter struct {
+	dest  io.Writer
+	sizer Sizer
+	stats *Statistics
+}
+
+func (np *ndjsonPrinter) PrintHeader(ct CounterType) {
+}
+
+func (np *ndjsonPrinter) PrintEach(fileName string, counter Counter, index int) {
+	np.PrintEntry(NewArg(fileName), counter, index)
+}
+
+func (np *ndjsonPrinter) PrintEntry(entry NameAndIndex, counter Counter, index int) {
+	writeJSONRecord(np.dest, entry.Name(), counter, recordKind(entry), np.sizer)
+	fmt.Fprintln(np.dest)
+}
+
+func (np *ndjsonPrinter) PrintError(err *errors.Error, index int) {
+	writeJSONError(np.dest, err)
+	fmt.Fprintln(np.dest)
+}
+
+func (np *ndjsonPrinter) PrintTotal(rs *ResultSet) {
+	writeJSONRecord(np.dest, "total", rs.total, "", np.sizer)
+	fmt.Fprintln(np.dest)
+}
+
+func (np *ndjsonPrinter) PrintStatistics(stats *Statistics) {
+	fmt.Fprint(np.dest, `{"type":"statistics",`)
+	writeJSONStatistics(np.dest, stats, np.sizer)
+	fmt.Fprintln(np.dest, `}`)
+}
+
+func (np *ndjsonPrinter) PrintFooter() {
 }
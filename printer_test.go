@@ -1,7 +1,9 @@
 package wildcat
 
 import (
+	"encoding/csv"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -33,14 +35,29 @@ func TestDefaultPrinter(t *testing.T) {
 	rs := createResultSetForTest()
 	rs.Print(NewPrinter(writer, "unknown", &defaultSizer{}))
 	result := writer.String()
-	if !strings.Contains(result, `4         26        142        142 testdata/wc/humpty_dumpty.txt`) {
-		t.Errorf("the result by DefaultPrinter did not contains humpty_dumpty.txt, got %s", result)
+	wont := ` lines words characters bytes
+     4    26        142   142 testdata/wc/humpty_dumpty.txt
+    15    26        118   298 testdata/wc/ja/sakura_sakura.txt
+    19    52        260   440 total (2 entries)
+`
+	if result != wont {
+		t.Errorf("the result by DefaultPrinter did not match, wont %s, got %s", wont, result)
 	}
-	if !strings.Contains(result, `15         26        118        298 testdata/wc/ja/sakura_sakura.txt`) {
-		t.Errorf("the result by DefaultPrinter did not contains sakura_sakura.txt, got %s", result)
+}
+
+func TestDefaultPrinterWithColor(t *testing.T) {
+	writer := new(strings.Builder)
+	rs := createResultSetForTest()
+	rs.Print(NewDefaultPrinter(writer, BuildSizer(true), true))
+	result := writer.String()
+	if !strings.HasPrefix(result, "\x1b[1m lines words characters bytes\x1b[0m\n") {
+		t.Errorf("the header of DefaultPrinter was not colored, got %q", result)
+	}
+	if !strings.Contains(result, "    15    26        118 298 B testdata/wc/ja/sakura_sakura.txt\n") {
+		t.Errorf("the columns of DefaultPrinter did not fit to the humanized values, got %q", result)
 	}
-	if !strings.Contains(result, `19         52        260        440 total`) {
-		t.Errorf("the result by DefaultPrinter did not contains total, got %s", result)
+	if !strings.HasSuffix(result, "\x1b[1;36m    19    52        260 440 B total (2 entries)\x1b[0m\n") {
+		t.Errorf("the total of DefaultPrinter was not colored, got %q", result)
 	}
 }
 
@@ -60,18 +77,156 @@ func TestJsonPrinter(t *testing.T) {
 	}
 }
 
-func TestCsvPrinter(t *testing.T) {
+func TestNdjsonPrinter(t *testing.T) {
 	writer := new(strings.Builder)
 	rs := createResultSetForTest()
+	rs.Print(NewPrinter(writer, "ndjson", &defaultSizer{}))
+	wont := `{"filename":"testdata/wc/humpty_dumpty.txt","lines":"4","words":"26","characters":"142","bytes":"142"}
+{"filename":"testdata/wc/ja/sakura_sakura.txt","lines":"15","words":"26","characters":"118","bytes":"298"}
+{"filename":"total","lines":"19","words":"52","characters":"260","bytes":"440"}
+`
+	if writer.String() != wont {
+		t.Errorf("the result by NdjsonPrinter did not match, wont %s, got %s", wont, writer.String())
+	}
+	jsonWriter := new(strings.Builder)
+	createResultSetForTest().Print(NewPrinter(jsonWriter, "json", &defaultSizer{}))
+	for _, line := range strings.Split(strings.TrimSpace(wont), "\n") {
+		if !strings.Contains(jsonWriter.String(), line) {
+			t.Errorf("ndjson record %s did not match to the json records, got %s", line, jsonWriter.String())
+		}
+	}
+}
+
+func TestCsvPrinter(t *testing.T) {
+	testdata := []struct {
+		giveOpts   *CsvOptions
+		wontResult string
+	}{
+		{&CsvOptions{Delimiter: ','}, "type,name,lines,words,characters,bytes\nentry,testdata/wc/humpty_dumpty.txt,4,26,142,142\nentry,testdata/wc/ja/sakura_sakura.txt,15,26,118,298\ntotal,total,19,52,260,440\n"},
+		{&CsvOptions{Delimiter: '\t', NoHeader: true}, "entry\ttestdata/wc/humpty_dumpty.txt\t4\t26\t142\t142\nentry\ttestdata/wc/ja/sakura_sakura.txt\t15\t26\t118\t298\ntotal\ttotal\t19\t52\t260\t440\n"},
+		{&CsvOptions{QuoteAll: true, NoHeader: true}, "\"entry\",\"testdata/wc/humpty_dumpty.txt\",\"4\",\"26\",\"142\",\"142\"\n\"entry\",\"testdata/wc/ja/sakura_sakura.txt\",\"15\",\"26\",\"118\",\"298\"\n\"total\",\"total\",\"19\",\"52\",\"260\",\"440\"\n"},
+	}
+	for _, td := range testdata {
+		writer := new(strings.Builder)
+		rs := createResultSetForTest()
+		rs.Print(NewCsvPrinter(writer, td.giveOpts))
+		if writer.String() != td.wontResult {
+			t.Errorf("the result by CsvPrinter did not match, wont %q, got %q", td.wontResult, writer.String())
+		}
+	}
+}
+
+func TestCsvPrinterQuotesFileNames(t *testing.T) {
+	writer := new(strings.Builder)
+	printer := NewPrinter(writer, "csv", &defaultSizer{})
+	printer.PrintHeader(Lines)
+	printer.PrintEach(`a,"b".txt`, NewCounter(Lines), 0)
+	printer.PrintFooter()
+	if !strings.Contains(writer.String(), `entry,"a,""b"".txt",0`) {
+		t.Errorf("file name in csv was not quoted, got %s", writer.String())
+	}
+}
+
+// namePrinter is the printer implementing only Printer, like the printers out of this package.
+type namePrinter struct {
+	names []string
+}
+
+func (np *namePrinter) PrintHeader(ct CounterType) {}
+
+func (np *namePrinter) PrintEach(fileName string, counter Counter, index int) {
+	np.names = append(np.names, fileName)
+}
+
+func (np *namePrinter) PrintTotal(rs *ResultSet) {
+	np.names = append(np.names, "total")
+}
+
+func (np *namePrinter) PrintFooter() {}
+
+func TestPrinterWithoutEntries(t *testing.T) {
+	testdata := []struct {
+		giveRollup *Rollup
+		wontNames  []string
+	}{
+		{&Rollup{}, []string{"testdata/wc/humpty_dumpty.txt", "testdata/wc/ja/sakura_sakura.txt", "total"}},
+		{&Rollup{Enabled: true}, []string{"testdata/wc/humpty_dumpty.txt", "testdata/wc/ja/sakura_sakura.txt", "total"}},
+	}
+	for _, td := range testdata {
+		rs := createResultSetForTest()
+		rs.SetRollup(td.giveRollup)
+		rs.SetStatistics(&StatisticsOptions{Enabled: true})
+		printer := &namePrinter{}
+		rs.Print(printer)
+		if strings.Join(printer.names, ",") != strings.Join(td.wontNames, ",") {
+			t.Errorf("printed names did not match, wont %v, got %v", td.wontNames, printer.names)
+		}
+	}
+}
+
+func TestErrorRecords(t *testing.T) {
+	testdata := []struct {
+		giveFormat string
+		wontRecord string
+	}{
+		{"json", `{"filename":"testdata/not_exist.txt","type":"error","kind":"not-found","order":"1","message":"testdata/not_exist.txt: file or directory not found"}`},
+		{"xml", `<error kind="not-found"><file-name>testdata/not_exist.txt</file-name><order>1</order><message>testdata/not_exist.txt: file or directory not found</message></error>`},
+		{"ndjson", `{"filename":"testdata/not_exist.txt","type":"error","kind":"not-found","order":"1","message":"testdata/not_exist.txt: file or directory not found"}` + "\n"},
+		{"csv", "error,testdata/not_exist.txt,testdata/not_exist.txt: file or directory not found,,,\n"},
+		{"default", ""},
+	}
+	for _, td := range testdata {
+		argf := NewArgf([]string{"testdata/wc/humpty_dumpty.txt", "testdata/not_exist.txt"}, &ReadOptions{}, &RuntimeOptions{})
+		rs, ec := NewWildcat(argf.Options, argf.RuntimeOpts, DefaultGenerator).CountAll(argf)
+		if ec.Size() != 1 || rs.Size() != 1 {
+			t.Errorf("%s: wont 1 result and 1 error, got %d results and %d errors", td.giveFormat, rs.Size(), ec.Size())
+		}
+		writer := new(strings.Builder)
+		rs.Print(NewPrinter(writer, td.giveFormat, &defaultSizer{}))
+		result := writer.String()
+		if td.wontRecord == "" && strings.Contains(result, "not_exist.txt") {
+			t.Errorf("%s: error record should not be printed, got %s", td.giveFormat, result)
+		}
+		if !strings.Contains(result, td.wontRecord) {
+			t.Errorf("%s: error record not found, wont %s, got %s", td.giveFormat, td.wontRecord, result)
+		}
+	}
+}
+
+func TestCsvErrorRecordsAreValid(t *testing.T) {
+	argf := NewArgf([]string{"testdata/wc/humpty_dumpty.txt", "testdata/not_exist.txt"}, &ReadOptions{}, &RuntimeOptions{})
+	rs, _ := NewWildcat(argf.Options, argf.RuntimeOpts, DefaultGenerator).CountAll(argf)
+	writer := new(strings.Builder)
 	rs.Print(NewPrinter(writer, "csv", &defaultSizer{}))
-	result := writer.String()
-	if !strings.Contains(result, `testdata/wc/humpty_dumpty.txt,"4","26","142","142"`) {
-		t.Errorf("the result by CsvPrinter did not contains humpty_dumpty.txt, got %s", result)
+	records, err := csv.NewReader(strings.NewReader(writer.String())).ReadAll()
+	if err != nil {
+		t.Errorf("printed csv was invalid: %s, got %s", err.Error(), writer.String())
+	}
+	if len(records) != 3 || records[2][0] != "error" {
+		t.Errorf("printed csv did not contain the error record, got %v", records)
 	}
-	if !strings.Contains(result, `testdata/wc/ja/sakura_sakura.txt,"15","26","118","298"`) {
-		t.Errorf("the result by CsvPrinter did not contains sakura_sakura.txt, got %s", result)
+}
+
+func TestConcurrentPrint(t *testing.T) {
+	rs := countForRollupTest("testdata/wc")
+	rs.SetSortOrder(&SortOrder{Key: SortByBytes, Descending: true})
+	wont := new(strings.Builder)
+	rs.Print(NewPrinter(wont, "csv", &defaultSizer{}))
+	group := new(sync.WaitGroup)
+	results := make([]string, 8)
+	for i := range results {
+		group.Add(1)
+		go func(index int) {
+			defer group.Done()
+			writer := new(strings.Builder)
+			rs.Print(NewPrinter(writer, "csv", &defaultSizer{}))
+			results[index] = writer.String()
+		}(i)
 	}
-	if !strings.Contains(result, `total,"19","52","260","440"`) {
-		t.Errorf("the result by CsvPrinter did not contains total, got %s", result)
+	group.Wait()
+	for _, result := range results {
+		if result != wont.String() {
+			t.Errorf("concurrently printed results did not match, wont %s, got %s", wont.String(), result)
+		}
 	}
 }
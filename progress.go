@@ -3,18 +3,41 @@ package wildcat
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 
 	"github.com/vbauerster/mpb/v6"
 	"github.com/vbauerster/mpb/v6/decor"
-	"golang.org/x/sync/semaphore"
 )
 
+// Progress manages the number of counting targets in progress.
+// The number of targets running concurrently is not limited by Progress, but by the worker pool of Wildcat.
+// UpdateTarget adds a target, and Done must be called for each target added.
+// If the Progress implements ContextProgress, Wildcat calls UpdateTargetContext instead of UpdateTarget.
 type Progress interface {
 	UpdateTarget()
 	Wait()
 	Done()
 }
 
+// ContextProgress is the Progress which refuses to add the targets after the given context is done.
+// UpdateTargetContext adds a target, and returns the error of the given context if it is done before adding the target.
+type ContextProgress interface {
+	Progress
+	UpdateTargetContext(ctx context.Context) error
+}
+
+// updateTarget adds a target to the given progress unless the given context is done.
+func updateTarget(ctx context.Context, progress Progress) error {
+	if cp, ok := progress.(ContextProgress); ok {
+		return cp.UpdateTargetContext(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	progress.UpdateTarget()
+	return nil
+}
+
 func initProgressBar(progress Progress) *ProgressBar {
 	p := mpb.New(mpb.WithWidth(64))
 	bar := p.Add(0,
@@ -29,12 +52,17 @@ func initProgressBar(progress Progress) *ProgressBar {
 	return &ProgressBar{progress: progress, total: 0, bar: bar, mpb: p}
 }
 
+// NewProgress creates an instance of Progress, which shows the progress bar if showBar is true.
+//
+// Deprecated: max is ignored, since the number of targets running concurrently is limited by RuntimeOptions.ThreadNumber.
+// Use NewTargetProgress instead.
 func NewProgress(showBar bool, max int64) Progress {
-	group := new(sync.WaitGroup)
-	var progress Progress = &nullProgress{group: group}
-	if max > 0 {
-		progress = &limittedProgress{group: group, sem: semaphore.NewWeighted(max)}
-	}
+	return NewTargetProgress(showBar)
+}
+
+// NewTargetProgress creates an instance of ContextProgress, which shows the progress bar if showBar is true.
+func NewTargetProgress(showBar bool) ContextProgress {
+	var progress ContextProgress = &nullProgress{group: new(sync.WaitGroup)}
 	if showBar {
 		progress = initProgressBar(progress)
 	}
@@ -50,14 +78,20 @@ type ProgressBar struct {
 
 func (pb *ProgressBar) Wait() {
 	pb.progress.Wait()
-	pb.bar.SetTotal(pb.total, true)
+	pb.bar.SetTotal(atomic.LoadInt64(&pb.total), true)
 	pb.mpb.Wait()
 }
 
 func (pb *ProgressBar) UpdateTarget() {
-	pb.progress.UpdateTarget()
-	pb.total = pb.total + 1
-	pb.bar.SetTotal(pb.total, false)
+	pb.UpdateTargetContext(context.Background())
+}
+
+func (pb *ProgressBar) UpdateTargetContext(ctx context.Context) error {
+	if err := updateTarget(ctx, pb.progress); err != nil {
+		return err
+	}
+	pb.bar.SetTotal(atomic.AddInt64(&pb.total, 1), false)
+	return nil
 }
 
 func (pb *ProgressBar) Done() {
@@ -65,25 +99,6 @@ func (pb *ProgressBar) Done() {
 	pb.bar.Increment()
 }
 
-type limittedProgress struct {
-	sem   *semaphore.Weighted
-	group *sync.WaitGroup
-}
-
-func (lp *limittedProgress) Wait() {
-	lp.group.Wait()
-}
-
-func (lp *limittedProgress) UpdateTarget() {
-	lp.sem.Acquire(context.Background(), 1)
-	lp.group.Add(1)
-}
-
-func (lp *limittedProgress) Done() {
-	lp.sem.Release(1)
-	lp.group.Done()
-}
-
 type nullProgress struct {
 	group *sync.WaitGroup
 }
@@ -96,6 +111,14 @@ func (np *nullProgress) UpdateTarget() {
 	np.group.Add(1)
 }
 
+func (np *nullProgress) UpdateTargetContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	np.group.Add(1)
+	return nil
+}
+
 func (np *nullProgress) Done() {
 	np.group.Done()
 }
@@ -2,21 +2,23 @@ package wildcat
 
 import (
 	"fmt"
-	"sort"
 
 	"github.com/dustin/go-humanize"
 )
 
 // Either shows either the list of result or error.
+// Groups shows the containers (directories, archives, and file lists) of the results for building subtotals.
 type Either struct {
 	Err     error
 	Results []*Result
+	Groups  []NameAndIndex
 }
 
 // Result is the counted result of each entry.
 type Result struct {
 	nameIndex NameAndIndex
 	counter   Counter
+	archived  bool
 }
 
 func newResult(entry NameAndIndex, counter Counter) *Result {
@@ -28,14 +30,29 @@ func newResult(entry NameAndIndex, counter Counter) *Result {
 
 // ResultSet shows the set of results.
 type ResultSet struct {
-	results map[string]Counter
-	list    []NameAndIndex
-	total   *totalCounter
+	results  map[string]Counter
+	list     []NameAndIndex
+	total    *totalCounter
+	order    *SortOrder
+	groups   map[string]NameAndIndex
+	rollup   *Rollup
+	stats    *StatisticsOptions
+	errs     []error
+	archived int
 }
 
 // NewResultSet creates an instance of ResultSet.
 func NewResultSet() *ResultSet {
-	return &ResultSet{results: map[string]Counter{}, list: []NameAndIndex{}, total: &totalCounter{}}
+	return &ResultSet{results: map[string]Counter{}, list: []NameAndIndex{}, total: &totalCounter{}, order: &SortOrder{}, groups: map[string]NameAndIndex{}, rollup: &Rollup{}, stats: &StatisticsOptions{}}
+}
+
+// SetSortOrder sets the order for printing results.
+// The total is computed from all of results, even if the printed results are truncated by SortOrder.Top.
+func (rs *ResultSet) SetSortOrder(order *SortOrder) {
+	if order == nil {
+		order = &SortOrder{}
+	}
+	rs.order = order
 }
 
 // Size returns the file count in the ResultSet.
@@ -43,38 +60,80 @@ func (rs *ResultSet) Size() int {
 	return len(rs.list)
 }
 
+// Errors returns the errors occurred in counting the entries of the ResultSet.
+func (rs *ResultSet) Errors() []error {
+	return rs.errs
+}
+
+// TotalCount returns the total count of the given counter type in the ResultSet.
+func (rs *ResultSet) TotalCount(ct CounterType) int64 {
+	return rs.total.Count(ct)
+}
+
+// ArchivedSize returns the number of the results extracted from the archives in the ResultSet.
+func (rs *ResultSet) ArchivedSize() int {
+	return rs.archived
+}
+
 // CounterType returns the types of counter of the ResultSet.
 func (rs *ResultSet) CounterType() CounterType {
 	return rs.total.ct
 }
 
-func (rs *ResultSet) sort() {
-	// fmt.Fprintf(os.Stderr, "sorting...")
-	sort.SliceStable(rs.list, func(i, j int) bool {
-		return rs.list[i].Index().Compare(rs.list[j].Index()) < 0
-	})
-	// fmt.Fprintf(os.Stderr, "done\n")
-}
-
 // Print prints the content of receiver ResultSet instance through given printer.
+// Print does not modify the receiver, therefore, it is safe to print a ResultSet from multiple goroutines concurrently.
 func (rs *ResultSet) Print(printer Printer) error {
-	rs.sort()
-	index := 0
+	if rs.rollup.Enabled || requiresRollup(printer) {
+		return rs.printRollup(printer)
+	}
+	sorted := rs.order.sort(rs)
 	printer.PrintHeader(rs.total.ct)
-	for _, name := range rs.list {
-		printer.PrintEach(name.Name(), rs.Counter(name.Name()), index)
-		index++
+	printed := sorted[:rs.order.limit(len(sorted))]
+	for index, name := range printed {
+		printEach(printer, name, rs.Counter(name.Name()), index)
 	}
-	if index > 1 {
+	rs.printErrors(printer, len(printed))
+	return rs.printTail(printer)
+}
+
+// printErrors prints the errors as the records in the order of the entries through the given printer, if the printer implements ErrorPrinter.
+// The index of the first error record is the given index.
+func (rs *ResultSet) printErrors(printer Printer, index int) {
+	ep, ok := printer.(ErrorPrinter)
+	if !ok {
+		return
+	}
+	for i, err := range sortErrors(rs.errs) {
+		ep.PrintError(err, index+i)
+	}
+}
+
+// printTail prints the total, the statistics summary if enabled, and the footer through the given printer.
+func (rs *ResultSet) printTail(printer Printer) error {
+	if rs.Size() > 1 {
 		printer.PrintTotal(rs)
 	}
+	if sp, ok := printer.(StatisticsPrinter); ok && rs.stats.Enabled {
+		sp.PrintStatistics(rs.Statistics(rs.stats.HistogramType))
+	}
 	printer.PrintFooter()
+	return printerError(printer)
+}
+
+// printerError returns the error occurred in the given printer, if the printer reports it.
+func printerError(printer Printer) error {
+	if reporter, ok := printer.(interface{ Err() error }); ok {
+		return reporter.Err()
+	}
 	return nil
 }
 
 // Push adds the given result to the receiver ResultSet.
 func (rs *ResultSet) Push(r *Result) {
 	rs.push(r.nameIndex, r.counter)
+	if r.archived {
+		rs.archived++
+	}
 }
 
 // Push stores given counter with given fileName to the receiver ResultSet.
@@ -84,6 +143,10 @@ func (rs *ResultSet) push(nai NameAndIndex, counter Counter) {
 	updateTotal(rs.total, counter)
 }
 
+func (rs *ResultSet) pushGroup(group NameAndIndex) {
+	rs.groups[group.Index().String()] = group
+}
+
 // Counter returns the object of Counter corresponding the given fileName.
 func (rs *ResultSet) Counter(fileName string) Counter {
 	return rs.results[fileName]
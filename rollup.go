@@ -0,0 +1,127 @@
+package wildcat
+
+import (
+	"sort"
+)
+
+// Rollup represents the settings for printing the subtotals of the directories, archives, and file lists.
+type Rollup struct {
+	Enabled bool
+	// Depth is the max depth of printed results, zero or less means unlimited.
+	// The results deeper than Depth are rolled up into the subtotal of their ancestor.
+	Depth int
+}
+
+// SetRollup sets the settings for printing subtotals.
+// In the rollup mode, the siblings are sorted by SortOrder, and SortOrder.Top limits the number of siblings.
+func (rs *ResultSet) SetRollup(rollup *Rollup) {
+	if rollup == nil {
+		rollup = &Rollup{}
+	}
+	rs.rollup = rollup
+}
+
+// SubtotalEntry is the entry for representing the subtotal of a directory, archive, or file list.
+// The printers receive this type through EntryPrinter.PrintEntry in the rollup mode.
+type SubtotalEntry struct {
+	group NameAndIndex
+}
+
+// Name returns the name of the directory, archive, or file list.
+func (se *SubtotalEntry) Name() string {
+	return se.group.Name()
+}
+
+// Index returns the index of the directory, archive, or file list.
+func (se *SubtotalEntry) Index() *Order {
+	return se.group.Index()
+}
+
+// IsSubtotal checks the given entry shows the subtotal or not.
+func IsSubtotal(entry NameAndIndex) bool {
+	_, ok := entry.(*SubtotalEntry)
+	return ok
+}
+
+// rollupPrinter is implemented by the printers which always print the results in the rollup mode.
+type rollupPrinter interface {
+	requiresRollup() bool
+}
+
+func requiresRollup(printer Printer) bool {
+	rp, ok := printer.(rollupPrinter)
+	return ok && rp.requiresRollup()
+}
+
+type resultNode struct {
+	entry    NameAndIndex
+	counter  Counter
+	children []*resultNode
+}
+
+func (node *resultNode) depth() int {
+	return node.entry.Index().depth()
+}
+
+// buildTree builds the tree of results by their hierarchical Order, and the subtotals of the groups.
+func (rs *ResultSet) buildTree() []*resultNode {
+	root := &resultNode{}
+	nodes := map[string]*resultNode{}
+	for _, entry := range rs.list {
+		parent := rs.findParentNode(root, nodes, entry.Index().parent)
+		counter := rs.Counter(entry.Name())
+		parent.children = append(parent.children, &resultNode{entry: entry, counter: counter})
+		for order := entry.Index().parent; order != nil; order = order.parent {
+			updateTotal(nodes[order.String()].counter.(*totalCounter), counter)
+		}
+	}
+	return root.children
+}
+
+func (rs *ResultSet) findParentNode(root *resultNode, nodes map[string]*resultNode, order *Order) *resultNode {
+	if order == nil {
+		return root
+	}
+	key := order.String()
+	if node, ok := nodes[key]; ok {
+		return node
+	}
+	parent := rs.findParentNode(root, nodes, order.parent)
+	group, ok := rs.groups[key]
+	if !ok {
+		group = NewArgWithIndex(order, key)
+	}
+	node := &resultNode{entry: &SubtotalEntry{group: group}, counter: &totalCounter{}}
+	nodes[key] = node
+	parent.children = append(parent.children, node)
+	return node
+}
+
+func (rs *ResultSet) sortNodes(nodes []*resultNode) []*resultNode {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return rs.order.compare(nodes[i].entry, nodes[j].entry, nodes[i].counter, nodes[j].counter) < 0
+	})
+	return nodes[:rs.order.limit(len(nodes))]
+}
+
+// flatten lists the given nodes in the pre-order, with sorting the siblings and pruning the nodes deeper than the depth.
+func (rs *ResultSet) flatten(nodes []*resultNode, results []*resultNode) []*resultNode {
+	for _, node := range rs.sortNodes(nodes) {
+		if rs.rollup.Depth > 0 && node.depth() > rs.rollup.Depth {
+			continue
+		}
+		results = append(results, node)
+		results = rs.flatten(node.children, results)
+	}
+	return results
+}
+
+func (rs *ResultSet) printRollup(printer Printer) error {
+	printer.PrintHeader(rs.total.ct)
+	nodes := rs.flatten(rs.buildTree(), []*resultNode{})
+	for index, node := range nodes {
+		printEach(printer, node.entry, node.counter, index)
+	}
+	rs.printErrors(printer, len(nodes))
+	return rs.printTail(printer)
+}
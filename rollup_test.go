@@ -0,0 +1,76 @@
+package wildcat
+
+import (
+	"strings"
+	"testing"
+)
+
+func countForRollupTest(args ...string) *ResultSet {
+	argf := NewArgf(args, &ReadOptions{}, &RuntimeOptions{ThreadNumber: 10})
+	rs, _ := NewWildcat(argf.Options, argf.RuntimeOpts, DefaultGenerator).CountAll(argf)
+	return rs
+}
+
+func TestSubtotal(t *testing.T) {
+	testdata := []struct {
+		giveRollup *Rollup
+		wontResult string
+	}{
+		{&Rollup{Enabled: true}, `type,name,lines,words,characters,bytes
+subtotal,testdata/wc,78,312,1601,1781
+entry,testdata/wc/humpty_dumpty.txt,4,26,142,142
+subtotal,testdata/wc/ja,15,26,118,298
+entry,testdata/wc/ja/sakura_sakura.txt,15,26,118,298
+entry,testdata/wc/london_bridge_is_broken_down.txt,59,260,1341,1341
+subtotal,testdata/archives/wc.tar.gz,78,312,1601,1781
+entry,testdata/archives/wc.tar.gz!humpty_dumpty.txt,4,26,142,142
+entry,testdata/archives/wc.tar.gz!ja/,0,0,0,0
+entry,testdata/archives/wc.tar.gz!ja/sakura_sakura.txt,15,26,118,298
+entry,testdata/archives/wc.tar.gz!london_bridge_is_broken_down.txt,59,260,1341,1341
+total,total,156,624,3202,3562
+`},
+		{&Rollup{Enabled: true, Depth: 1}, `type,name,lines,words,characters,bytes
+subtotal,testdata/wc,78,312,1601,1781
+subtotal,testdata/archives/wc.tar.gz,78,312,1601,1781
+total,total,156,624,3202,3562
+`},
+	}
+	for _, td := range testdata {
+		rs := countForRollupTest("testdata/wc", "testdata/archives/wc.tar.gz")
+		rs.SetRollup(td.giveRollup)
+		writer := new(strings.Builder)
+		rs.Print(NewPrinter(writer, "csv", &defaultSizer{}))
+		if writer.String() != td.wontResult {
+			t.Errorf("rollup (%v) did not match, wont %s, got %s", td.giveRollup, td.wontResult, writer.String())
+		}
+	}
+}
+
+func TestTreePrinter(t *testing.T) {
+	rs := countForRollupTest("testdata/wc", "testdata/wc/humpty_dumpty.txt")
+	writer := new(strings.Builder)
+	rs.Print(NewPrinter(writer, "tree", &defaultSizer{}))
+	wont := ` lines words characters bytes
+    78   312       1601  1781 testdata/wc
+     4    26        142   142 ├── humpty_dumpty.txt
+    15    26        118   298 ├── ja
+    15    26        118   298 │   └── sakura_sakura.txt
+    59   260       1341  1341 └── london_bridge_is_broken_down.txt
+     4    26        142   142 testdata/wc/humpty_dumpty.txt
+    82   338       1743  1923 total (4 entries)
+`
+	if writer.String() != wont {
+		t.Errorf("the result by TreePrinter did not match, wont %s, got %s", wont, writer.String())
+	}
+}
+
+func TestFindLasts(t *testing.T) {
+	depths := []int{1, 2, 2, 3, 2, 1, 2}
+	wonts := []bool{false, false, false, true, true, true, true}
+	lasts := findLasts(depths)
+	for i, wont := range wonts {
+		if lasts[i] != wont {
+			t.Errorf("findLasts(%v)[%d] did not match, wont %v, got %v", depths, i, wont, lasts[i])
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package wildcat
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tamada/wildcat/errors"
+)
+
+// SortKey represents the key for sorting the results.
+type SortKey int
+
+const (
+	// SortByOrder sorts the results by the order of the arguments (default).
+	SortByOrder SortKey = iota
+	// SortByName sorts the results by their names.
+	SortByName
+	// SortByLines sorts the results by the number of lines.
+	SortByLines
+	// SortByWords sorts the results by the number of words.
+	SortByWords
+	// SortByCharacters sorts the results by the number of characters.
+	SortByCharacters
+	// SortByBytes sorts the results by the number of bytes.
+	SortByBytes
+)
+
+// SortOrder shows how to sort and truncate the results for printing.
+type SortOrder struct {
+	Key        SortKey
+	Descending bool
+	// Top is the max number of printed results, zero or less means all.
+	Top int
+}
+
+var sortKeys = map[string]SortKey{
+	"":           SortByOrder,
+	"order":      SortByOrder,
+	"name":       SortByName,
+	"lines":      SortByLines,
+	"words":      SortByWords,
+	"chars":      SortByCharacters,
+	"characters": SortByCharacters,
+	"bytes":      SortByBytes,
+}
+
+// ParseSortOrder parses the given string in the form of KEY[:asc|:desc], and creates an instance of SortOrder.
+// Available keys are: order, name, lines, words, chars (characters), and bytes.
+// If the direction is omitted, the counts are sorted in the descending order, and the others are in the ascending order.
+func ParseSortOrder(str string, top int) (*SortOrder, error) {
+	items := strings.SplitN(strings.ToLower(strings.TrimSpace(str)), ":", 2)
+	key, ok := sortKeys[items[0]]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown sort key", items[0])
+	}
+	if top < 0 {
+		return nil, fmt.Errorf("%d: top must be zero or positive", top)
+	}
+	order := &SortOrder{Key: key, Descending: key.isCount(), Top: top}
+	if len(items) == 2 {
+		switch items[1] {
+		case "asc":
+			order.Descending = false
+		case "desc":
+			order.Descending = true
+		default:
+			return nil, fmt.Errorf("%s: unknown sort direction", items[1])
+		}
+	}
+	return order, nil
+}
+
+func (key SortKey) isCount() bool {
+	return key == SortByLines || key == SortByWords || key == SortByCharacters || key == SortByBytes
+}
+
+func (key SortKey) counterType() CounterType {
+	switch key {
+	case SortByLines:
+		return Lines
+	case SortByWords:
+		return Words
+	case SortByCharacters:
+		return Characters
+	default:
+		return Bytes
+	}
+}
+
+// compare compares the given two results, and the ties are broken by their Order.
+func (so *SortOrder) compare(a, b NameAndIndex, counterA, counterB Counter) int {
+	result := 0
+	switch {
+	case so.Key == SortByName:
+		result = strings.Compare(a.Name(), b.Name())
+	case so.Key.isCount():
+		ct := so.Key.counterType()
+		result = compareInt64(counterA.Count(ct), counterB.Count(ct))
+	}
+	if so.Descending {
+		result = -result
+	}
+	if result == 0 {
+		return a.Index().Compare(b.Index())
+	}
+	return result
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// sort returns the sorted copy of the entries in the given ResultSet, the ResultSet itself is not modified.
+func (so *SortOrder) sort(rs *ResultSet) []NameAndIndex {
+	list := make([]NameAndIndex, len(rs.list))
+	copy(list, rs.list)
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		return so.compare(a, b, rs.Counter(a.Name()), rs.Counter(b.Name())) < 0
+	})
+	return list
+}
+
+// sortErrors returns the errors sorted by their orders, since the errors are stored in the order of their occurrences.
+// The errors without orders are placed at the last.
+func sortErrors(errs []error) []*errors.Error {
+	list := make([]*errors.Error, len(errs))
+	for i, err := range errs {
+		list[i] = errors.ToError(err)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return compareOrderStrings(list[i].Order, list[j].Order) < 0
+	})
+	return list
+}
+
+// compareOrderStrings compares the given string representations of Order (e.g., "1.2.3").
+func compareOrderStrings(a, b string) int {
+	if a == "" || b == "" {
+		return compareInt64(int64(len(b)), int64(len(a)))
+	}
+	indexesA, indexesB := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(indexesA) && i < len(indexesB); i++ {
+		indexA, _ := strconv.Atoi(indexesA[i])
+		indexB, _ := strconv.Atoi(indexesB[i])
+		if result := compareInt64(int64(indexA), int64(indexB)); result != 0 {
+			return result
+		}
+	}
+	return compareInt64(int64(len(indexesA)), int64(len(indexesB)))
+}
+
+func (so *SortOrder) limit(size int) int {
+	if so.Top > 0 && so.Top < size {
+		return so.Top
+	}
+	return size
+}
@@ -0,0 +1,100 @@
+package wildcat
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tamada/wildcat/errors"
+)
+
+func TestParseSortOrder(t *testing.T) {
+	testdata := []struct {
+		giveString     string
+		giveTop        int
+		wontKey        SortKey
+		wontDescending bool
+		wontError      bool
+	}{
+		{"", 0, SortByOrder, false, false},
+		{"name", 0, SortByName, false, false},
+		{"lines", 0, SortByLines, true, false},
+		{"Bytes:asc", 10, SortByBytes, false, false},
+		{"chars", 0, SortByCharacters, true, false},
+		{"name:desc", 0, SortByName, true, false},
+		{"size", 0, SortByOrder, false, true},
+		{"words:up", 0, SortByOrder, false, true},
+		{"words", -1, SortByOrder, false, true},
+	}
+	for _, td := range testdata {
+		order, err := ParseSortOrder(td.giveString, td.giveTop)
+		if (err != nil) != td.wontError {
+			t.Errorf("ParseSortOrder(%s, %d) error did not match, wont %v, got %v", td.giveString, td.giveTop, td.wontError, err)
+		}
+		if err != nil {
+			continue
+		}
+		if order.Key != td.wontKey || order.Descending != td.wontDescending || order.Top != td.giveTop {
+			t.Errorf("ParseSortOrder(%s, %d) did not match, got %v", td.giveString, td.giveTop, order)
+		}
+	}
+}
+
+func TestSortAndTop(t *testing.T) {
+	testdata := []struct {
+		giveSort  string
+		giveTop   int
+		wontNames []string
+	}{
+		{"", 0, []string{"humpty_dumpty.txt", "sakura_sakura.txt", "london_bridge_is_broken_down.txt"}},
+		{"bytes", 0, []string{"london_bridge_is_broken_down.txt", "sakura_sakura.txt", "humpty_dumpty.txt"}},
+		{"bytes:asc", 2, []string{"humpty_dumpty.txt", "sakura_sakura.txt"}},
+		{"words", 0, []string{"london_bridge_is_broken_down.txt", "humpty_dumpty.txt", "sakura_sakura.txt"}},
+		{"name:desc", 1, []string{"london_bridge_is_broken_down.txt"}},
+	}
+	for _, td := range testdata {
+		argf := NewArgf([]string{"testdata/wc"}, &ReadOptions{}, &RuntimeOptions{})
+		rs, _ := NewWildcat(argf.Options, argf.RuntimeOpts, DefaultGenerator).CountAll(argf)
+		order, _ := ParseSortOrder(td.giveSort, td.giveTop)
+		rs.SetSortOrder(order)
+		writer := new(strings.Builder)
+		rs.Print(NewPrinter(writer, "csv", &defaultSizer{}))
+		lines := strings.Split(strings.TrimSpace(writer.String()), "\n")
+		if len(lines) != len(td.wontNames)+2 {
+			t.Errorf("%s (top %d): the number of printed rows did not match, wont %d, got %d", td.giveSort, td.giveTop, len(td.wontNames)+2, len(lines))
+			continue
+		}
+		for i, name := range td.wontNames {
+			if !strings.Contains(lines[i+1], name) {
+				t.Errorf("%s (top %d): line %d did not match, wont %s, got %s", td.giveSort, td.giveTop, i, name, lines[i+1])
+			}
+		}
+		if lines[len(lines)-1] != "total,total,78,312,1601,1781" {
+			t.Errorf("%s (top %d): total should be computed from all results, got %s", td.giveSort, td.giveTop, lines[len(lines)-1])
+		}
+	}
+}
+
+func TestSortErrors(t *testing.T) {
+	testdata := []struct {
+		giveOrders []string
+		wontOrders []string
+	}{
+		{[]string{"2", "1", "10"}, []string{"1", "2", "10"}},
+		{[]string{"1.2", "1", "0.3", "1.10"}, []string{"0.3", "1", "1.2", "1.10"}},
+		{[]string{"", "3", "1"}, []string{"1", "3", ""}},
+	}
+	for _, td := range testdata {
+		errs := []error{}
+		for _, order := range td.giveOrders {
+			errs = append(errs, &errors.Error{Name: "name" + order, Order: order, Err: fmt.Errorf("error")})
+		}
+		got := []string{}
+		for _, err := range sortErrors(errs) {
+			got = append(got, err.Order)
+		}
+		if strings.Join(got, ",") != strings.Join(td.wontOrders, ",") {
+			t.Errorf("sortErrors(%v) did not match, wont %v, got %v", td.giveOrders, td.wontOrders, got)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package wildcat
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// StatisticsOptions represents the settings for printing the statistics summary of the results.
+type StatisticsOptions struct {
+	Enabled bool
+	// HistogramType is the counter type for the histogram, zero means no histogram.
+	HistogramType CounterType
+}
+
+// Statistics shows the statistics summary of counted entries.
+type Statistics struct {
+	EntryCount int
+	Type       CounterType
+	Summaries  map[CounterType]*Summary
+	Histogram  *Histogram
+}
+
+// Summary shows the statistics of a counter type across the entries.
+// Mean and Median are rounded to the nearest integers, and percentiles are computed by the nearest-rank method.
+type Summary struct {
+	Min    int64
+	Max    int64
+	Mean   int64
+	Median int64
+	P90    int64
+	P99    int64
+}
+
+// Histogram shows the distribution of a counter type across the entries.
+type Histogram struct {
+	Type CounterType
+	Bins []*Bin
+}
+
+// Bin is a bucket of Histogram, which counts the entries between Lower and Upper (inclusive).
+type Bin struct {
+	Lower int64
+	Upper int64
+	Count int
+}
+
+var statisticsLabels = []string{"min", "max", "mean", "median", "p90", "p99"}
+
+func (summary *Summary) values() []int64 {
+	return []int64{summary.Min, summary.Max, summary.Mean, summary.Median, summary.P90, summary.P99}
+}
+
+// ParseCounterType parses the given name (lines, words, chars (characters), or bytes) to the counter type.
+func ParseCounterType(name string) (CounterType, error) {
+	switch strings.ToLower(name) {
+	case "lines":
+		return Lines, nil
+	case "words":
+		return Words, nil
+	case "chars", "characters":
+		return Characters, nil
+	case "bytes":
+		return Bytes, nil
+	default:
+		return 0, fmt.Errorf("%s: unknown counter type", name)
+	}
+}
+
+func labelOf(ct CounterType) string {
+	for index, t := range types {
+		if t == ct {
+			return labels[index]
+		}
+	}
+	return ""
+}
+
+// SetStatistics sets the settings for printing the statistics summary.
+func (rs *ResultSet) SetStatistics(opts *StatisticsOptions) {
+	if opts == nil {
+		opts = &StatisticsOptions{}
+	}
+	rs.stats = opts
+}
+
+// Statistics computes the statistics summary of the entries in the receiver ResultSet.
+// The histogram is built if histogramType is one of the counted types.
+func (rs *ResultSet) Statistics(histogramType CounterType) *Statistics {
+	stats := &Statistics{EntryCount: len(rs.list), Type: rs.total.ct, Summaries: map[CounterType]*Summary{}}
+	for _, t := range types {
+		if rs.total.ct.IsType(t) {
+			stats.Summaries[t] = summarize(rs.values(t))
+		}
+	}
+	if histogramType != 0 && rs.total.ct.IsType(histogramType) {
+		stats.Histogram = buildHistogram(histogramType, rs.values(histogramType))
+	}
+	return stats
+}
+
+func (rs *ResultSet) values(ct CounterType) []int64 {
+	values := []int64{}
+	for _, entry := range rs.list {
+		values = append(values, rs.Counter(entry.Name()).Count(ct))
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	return values
+}
+
+func summarize(sorted []int64) *Summary {
+	if len(sorted) == 0 {
+		return &Summary{}
+	}
+	sum := 0.0
+	for _, value := range sorted {
+		sum += float64(value)
+	}
+	return &Summary{
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+		Mean:   int64(math.Round(sum / float64(len(sorted)))),
+		Median: median(sorted),
+		P90:    percentile(sorted, 90),
+		P99:    percentile(sorted, 99),
+	}
+}
+
+func median(sorted []int64) int64 {
+	length := len(sorted)
+	if length%2 == 1 {
+		return sorted[length/2]
+	}
+	return int64(math.Round(float64(sorted[length/2-1]+sorted[length/2]) / 2))
+}
+
+// percentile returns the value of the given percentile by the nearest-rank method.
+func percentile(sorted []int64, p int) int64 {
+	rank := int(math.Ceil(float64(p) / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// binIndex returns the index of the bins in powers of two: 0, 1, 2-3, 4-7, 8-15, and so on.
+func binIndex(value int64) int {
+	index := 0
+	for value > 0 {
+		value = value >> 1
+		index++
+	}
+	return index
+}
+
+// binRange returns the range of the bin of the given index, the last bin (63) is clamped to math.MaxInt64.
+func binRange(index int) (int64, int64) {
+	switch {
+	case index == 0:
+		return 0, 0
+	case index >= 63:
+		return 1 << 62, math.MaxInt64
+	}
+	return 1 << (index - 1), (1 << index) - 1
+}
+
+func buildHistogram(ct CounterType, sorted []int64) *Histogram {
+	histogram := &Histogram{Type: ct, Bins: []*Bin{}}
+	if len(sorted) == 0 {
+		return histogram
+	}
+	first, last := binIndex(sorted[0]), binIndex(sorted[len(sorted)-1])
+	for index := first; index <= last; index++ {
+		lower, upper := binRange(index)
+		histogram.Bins = append(histogram.Bins, &Bin{Lower: lower, Upper: upper})
+	}
+	for _, value := range sorted {
+		histogram.Bins[binIndex(value)-first].Count++
+	}
+	return histogram
+}
+
+const histogramBarWidth = 40
+
+// bar returns the bar of the given bin in the ASCII art scaled by the max count in the receiver histogram.
+func (histogram *Histogram) bar(bin *Bin) string {
+	max := 0
+	for _, b := range histogram.Bins {
+		if b.Count > max {
+			max = b.Count
+		}
+	}
+	if max == 0 {
+		return ""
+	}
+	length := int(math.Ceil(float64(bin.Count) * histogramBarWidth / float64(max)))
+	return strings.Repeat("#", length)
+}
+
+func (bin *Bin) label(ct CounterType, sizer Sizer) string {
+	if bin.Lower == bin.Upper {
+		return sizer.Convert(bin.Lower, ct)
+	}
+	return fmt.Sprintf("%s-%s", sizer.Convert(bin.Lower, ct), sizer.Convert(bin.Upper, ct))
+}
+
+// statisticsRows builds the table of the given statistics, the first column shows the names of statistics.
+func statisticsRows(stats *Statistics, sizer Sizer) *tableRows {
+	header := headerCells(stats.Type)
+	header[0] = fmt.Sprintf("statistics (%d entries)", stats.EntryCount)
+	if stats.EntryCount == 1 {
+		header[0] = "statistics (1 entry)"
+	}
+	table := &tableRows{}
+	table.append(header)
+	for index, label := range statisticsLabels {
+		row := []string{label}
+		for _, t := range types {
+			if summary, ok := stats.Summaries[t]; ok {
+				row = append(row, sizer.Convert(summary.values()[index], t))
+			}
+		}
+		table.append(row)
+	}
+	return table
+}
+
+// histogramRows builds the table of the given histogram, each row shows the range, the number of entries, and the bar.
+func histogramRows(histogram *Histogram, sizer Sizer) *tableRows {
+	table := &tableRows{lefts: map[int]bool{2: true}}
+	table.append([]string{fmt.Sprintf("histogram of %s", labelOf(histogram.Type)), "entries", ""})
+	for _, bin := range histogram.Bins {
+		table.append([]string{bin.label(histogram.Type, sizer), fmt.Sprintf("%d", bin.Count), histogram.bar(bin)})
+	}
+	return table
+}
+
+// statisticsTables returns the tables of the given statistics, and the histogram if available.
+func statisticsTables(stats *Statistics, sizer Sizer) []*tableRows {
+	tables := []*tableRows{statisticsRows(stats, sizer)}
+	if stats.Histogram != nil {
+		tables = append(tables, histogramRows(stats.Histogram, sizer))
+	}
+	return tables
+}
+
+// printPlainStatistics prints the given statistics in the plain text tables for the default and tree printers.
+func printPlainStatistics(dest io.Writer, stats *Statistics, sizer Sizer, color bool) {
+	for _, table := range statisticsTables(stats, sizer) {
+		fmt.Fprintln(dest)
+		widths := table.widths()
+		for index, row := range table.rows {
+			cells := []string{}
+			for i, cell := range row {
+				cells = append(cells, table.align(cell, i, widths[i]))
+			}
+			line := strings.TrimRight(strings.Join(cells, " "), " ")
+			if index == 0 {
+				line = decorate(line, headerStyle, color)
+			}
+			fmt.Fprintln(dest, line)
+		}
+	}
+}
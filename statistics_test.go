@@ -0,0 +1,142 @@
+package wildcat
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	testdata := []struct {
+		giveValues []int64
+		wontResult *Summary
+	}{
+		{[]int64{}, &Summary{}},
+		{[]int64{5}, &Summary{Min: 5, Max: 5, Mean: 5, Median: 5, P90: 5, P99: 5}},
+		{[]int64{1, 2, 3, 4}, &Summary{Min: 1, Max: 4, Mean: 3, Median: 3, P90: 4, P99: 4}},
+		{[]int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, &Summary{Min: 1, Max: 10, Mean: 6, Median: 6, P90: 9, P99: 10}},
+	}
+	for _, td := range testdata {
+		got := summarize(td.giveValues)
+		if *got != *td.wontResult {
+			t.Errorf("summarize(%v) did not match, wont %v, got %v", td.giveValues, td.wontResult, got)
+		}
+	}
+}
+
+func TestBuildHistogram(t *testing.T) {
+	testdata := []struct {
+		giveValues []int64
+		wontBins   []Bin
+	}{
+		{[]int64{}, []Bin{}},
+		{[]int64{0, 0, 1}, []Bin{{0, 0, 2}, {1, 1, 1}}},
+		{[]int64{4, 15, 59}, []Bin{{4, 7, 1}, {8, 15, 1}, {16, 31, 0}, {32, 63, 1}}},
+		{[]int64{math.MaxInt64}, []Bin{{1 << 62, math.MaxInt64, 1}}},
+	}
+	for _, td := range testdata {
+		histogram := buildHistogram(Lines, td.giveValues)
+		if len(histogram.Bins) != len(td.wontBins) {
+			t.Errorf("buildHistogram(%v) bins size did not match, wont %d, got %d", td.giveValues, len(td.wontBins), len(histogram.Bins))
+			continue
+		}
+		for index, bin := range histogram.Bins {
+			if *bin != td.wontBins[index] {
+				t.Errorf("buildHistogram(%v) bins[%d] did not match, wont %v, got %v", td.giveValues, index, td.wontBins[index], bin)
+			}
+		}
+	}
+}
+
+func TestParseCounterType(t *testing.T) {
+	testdata := []struct {
+		giveName   string
+		wontResult CounterType
+		wontError  bool
+	}{
+		{"lines", Lines, false},
+		{"Words", Words, false},
+		{"chars", Characters, false},
+		{"characters", Characters, false},
+		{"bytes", Bytes, false},
+		{"size", 0, true},
+	}
+	for _, td := range testdata {
+		got, err := ParseCounterType(td.giveName)
+		if (err != nil) != td.wontError {
+			t.Errorf("ParseCounterType(%s) wont error %v, got %v", td.giveName, td.wontError, err)
+		}
+		if got != td.wontResult {
+			t.Errorf("ParseCounterType(%s) did not match, wont %d, got %d", td.giveName, td.wontResult, got)
+		}
+	}
+}
+
+func TestPrintStatistics(t *testing.T) {
+	testdata := []struct {
+		giveFormat string
+		giveOpts   *StatisticsOptions
+		wontResult string
+	}{
+		{"csv", &StatisticsOptions{Enabled: true}, `type,name,lines,words,characters,bytes
+entry,testdata/wc/humpty_dumpty.txt,4,26,142,142
+entry,testdata/wc/ja/sakura_sakura.txt,15,26,118,298
+entry,testdata/wc/london_bridge_is_broken_down.txt,59,260,1341,1341
+total,total,78,312,1601,1781
+statistics,min,4,26,118,142
+statistics,max,59,260,1341,1341
+statistics,mean,26,104,534,594
+statistics,median,15,26,142,298
+statistics,p90,59,260,1341,1341
+statistics,p99,59,260,1341,1341
+`},
+		{"default", &StatisticsOptions{Enabled: true, HistogramType: Lines}, ` lines words characters bytes
+     4    26        142   142 testdata/wc/humpty_dumpty.txt
+    15    26        118   298 testdata/wc/ja/sakura_sakura.txt
+    59   260       1341  1341 testdata/wc/london_bridge_is_broken_down.txt
+    78   312       1601  1781 total (3 entries)
+
+statistics (3 entries) lines words characters bytes
+min                        4    26        118   142
+max                       59   260       1341  1341
+mean                      26   104        534   594
+median                    15    26        142   298
+p90                       59   260       1341  1341
+p99                       59   260       1341  1341
+
+histogram of lines entries
+4-7                      1 ########################################
+8-15                     1 ########################################
+16-31                    0
+32-63                    1 ########################################
+`},
+	}
+	for _, td := range testdata {
+		rs := countForRollupTest("testdata/wc")
+		rs.SetStatistics(td.giveOpts)
+		writer := new(strings.Builder)
+		rs.Print(NewPrinter(writer, td.giveFormat, &defaultSizer{}))
+		if writer.String() != td.wontResult {
+			t.Errorf("statistics in %s format did not match, wont %s, got %s", td.giveFormat, td.wontResult, writer.String())
+		}
+	}
+}
+
+func TestStatisticsHeader(t *testing.T) {
+	testdata := []struct {
+		giveArgs   []string
+		wontHeader string
+	}{
+		{[]string{"testdata/wc/humpty_dumpty.txt"}, "statistics (1 entry)"},
+		{[]string{"testdata/wc/humpty_dumpty.txt", "testdata/wc/ja/sakura_sakura.txt"}, "statistics (2 entries)"},
+	}
+	for _, td := range testdata {
+		rs := countForRollupTest(td.giveArgs...)
+		rs.SetStatistics(&StatisticsOptions{Enabled: true})
+		writer := new(strings.Builder)
+		rs.Print(NewPrinter(writer, "default", &defaultSizer{}))
+		if !strings.Contains(writer.String(), td.wontHeader+" ") {
+			t.Errorf("statistics header did not match, wont %s, got %s", td.wontHeader, writer.String())
+		}
+	}
+}
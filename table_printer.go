@@ -0,0 +1,407 @@
+package wildcat
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tamada/wildcat/errors"
+)
+
+// tableRows holds the cells of a table for printing them in aligned columns.
+// The first column shows the file names, and the rest columns show the counts.
+// lefts specifies the columns aligned to the left other than the first column.
+// spans specifies the rows whose second cell spans the rest columns, such as the error messages.
+type tableRows struct {
+	rows  [][]string
+	lefts map[int]bool
+	spans map[int]bool
+}
+
+func (tr *tableRows) isLeft(column int) bool {
+	return column == 0 || tr.lefts[column]
+}
+
+func (tr *tableRows) align(cell string, column, width int) string {
+	if tr.isLeft(column) {
+		return padRight(cell, width)
+	}
+	return padLeft(cell, width)
+}
+
+func (tr *tableRows) append(row []string) {
+	tr.rows = append(tr.rows, row)
+}
+
+// appendSpan appends the row whose second cell spans the rest columns.
+func (tr *tableRows) appendSpan(row []string) {
+	if tr.spans == nil {
+		tr.spans = map[int]bool{}
+	}
+	tr.spans[len(tr.rows)] = true
+	tr.append(row)
+}
+
+// widths returns the widths of the columns, the spanning cells are not considered.
+func (tr *tableRows) widths() []int {
+	widths := []int{}
+	for index, row := range tr.rows {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if tr.spans[index] && i > 0 {
+				continue
+			}
+			if length := utf8.RuneCountInString(cell); length > widths[i] {
+				widths[i] = length
+			}
+		}
+	}
+	return widths
+}
+
+func padRight(str string, width int) string {
+	return str + strings.Repeat(" ", maxInt(width-utf8.RuneCountInString(str), 0))
+}
+
+func padLeft(str string, width int) string {
+	return strings.Repeat(" ", maxInt(width-utf8.RuneCountInString(str), 0)) + str
+}
+
+func headerCells(ct CounterType) []string {
+	cells := []string{"file name"}
+	for index, label := range labels {
+		if ct.IsType(types[index]) {
+			cells = append(cells, label)
+		}
+	}
+	return cells
+}
+
+func countCells(fileName string, counter Counter, ct CounterType, sizer Sizer) []string {
+	cells := []string{fileName}
+	for _, t := range types {
+		if ct.IsType(t) {
+			cells = append(cells, sizer.Convert(counter.Count(t), t))
+		}
+	}
+	return cells
+}
+
+// errorCells returns the cells of the given error, the message is placed at the first column of the counts.
+func errorCells(name, message string, ct CounterType) []string {
+	cells := []string{name}
+	for _, t := range types {
+		if ct.IsType(t) {
+			cells = append(cells, "")
+		}
+	}
+	if len(cells) > 1 {
+		cells[1] = message
+	}
+	return cells
+}
+
+type markdownPrinter struct {
+	dest  io.Writer
+	sizer Sizer
+	ct    CounterType
+	table *tableRows
+	stats *Statistics
+}
+
+func escapeMarkdown(from string) string {
+	str := strings.ReplaceAll(from, "\\", "\\\\")
+	return strings.ReplaceAll(str, "|", "\\|")
+}
+
+func (mp *markdownPrinter) PrintHeader(ct CounterType) {
+	mp.ct = ct
+	mp.table = &tableRows{}
+	mp.table.append(headerCells(ct))
+}
+
+func (mp *markdownPrinter) PrintEach(fileName string, counter Counter, index int) {
+	mp.PrintEntry(NewArg(fileName), counter, index)
+}
+
+// PrintEntry prints the subtotals in italic.
+func (mp *markdownPrinter) PrintEntry(entry NameAndIndex, counter Counter, index int) {
+	name := escapeMarkdown(entry.Name())
+	if IsSubtotal(entry) {
+		name = fmt.Sprintf("*%s*", name)
+	}
+	mp.table.append(countCells(name, counter, mp.ct, mp.sizer))
+}
+
+// PrintError prints the error in the row with the message at the first column of the counts.
+func (mp *markdownPrinter) PrintError(err *errors.Error, index int) {
+	mp.table.append(errorCells(escapeMarkdown(err.Name), fmt.Sprintf("error: %s", escapeMarkdown(err.Error())), mp.ct))
+}
+
+func (mp *markdownPrinter) PrintTotal(rs *ResultSet) {
+	mp.table.append(countCells(fmt.Sprintf("**%s**", rs.total.Name()), rs.total, mp.ct, mp.sizer))
+}
+
+func (mp *markdownPrinter) PrintStatistics(stats *Statistics) {
+	mp.stats = stats
+}
+
+func (mp *markdownPrinter) PrintFooter() {
+	mp.printTable(mp.table)
+	if mp.stats == nil {
+		return
+	}
+	for _, table := range statisticsTables(mp.stats, mp.sizer) {
+		fmt.Fprintln(mp.dest)
+		mp.printTable(table)
+	}
+}
+
+func (mp *markdownPrinter) printTable(table *tableRows) {
+	widths := table.widths()
+	for index, row := range table.rows {
+		mp.printRow(table, row, widths)
+		if index == 0 {
+			mp.printSeparator(table, widths)
+		}
+	}
+}
+
+func (mp *markdownPrinter) printRow(table *tableRows, row []string, widths []int) {
+	for i, cell := range row {
+		fmt.Fprintf(mp.dest, "| %s ", table.align(cell, i, widths[i]))
+	}
+	fmt.Fprintln(mp.dest, "|")
+}
+
+func (mp *markdownPrinter) printSeparator(table *tableRows, widths []int) {
+	for i, width := range widths {
+		if table.isLeft(i) {
+			fmt.Fprintf(mp.dest, "| :%s ", strings.Repeat("-", maxInt(width-1, 2)))
+		} else {
+			fmt.Fprintf(mp.dest, "| %s: ", strings.Repeat("-", maxInt(width-1, 2)))
+		}
+	}
+	fmt.Fprintln(mp.dest, "|")
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+type htmlPrinter struct {
+	dest         io.Writer
+	sizer        Sizer
+	ct           CounterType
+	totalPrinted bool
+	stats        *Statistics
+}
+
+func (hp *htmlPrinter) PrintHeader(ct CounterType) {
+	hp.ct = ct
+	fmt.Fprintln(hp.dest, "<table>")
+	fmt.Fprintln(hp.dest, "  <thead>")
+	hp.printRow(headerCells(ct), "th", "", nil)
+	fmt.Fprintln(hp.dest, "  </thead>")
+	fmt.Fprintln(hp.dest, "  <tbody>")
+}
+
+// printRow prints the given cells in a row, the class attribute of the row is omitted if the given class is empty.
+func (hp *htmlPrinter) printRow(cells []string, tag, class string, table *tableRows) {
+	if class != "" {
+		fmt.Fprintf(hp.dest, `    <tr class="%s">`, class)
+	} else {
+		fmt.Fprint(hp.dest, "    <tr>")
+	}
+	for i, cell := range cells {
+		if i == 0 || (table != nil && table.isLeft(i)) {
+			fmt.Fprintf(hp.dest, `<%s style="text-align:left">%s</%s>`, tag, html.EscapeString(cell), tag)
+		} else {
+			fmt.Fprintf(hp.dest, `<%s style="text-align:right">%s</%s>`, tag, html.EscapeString(cell), tag)
+		}
+	}
+	fmt.Fprintln(hp.dest, "</tr>")
+}
+
+func (hp *htmlPrinter) PrintEach(fileName string, counter Counter, index int) {
+	hp.PrintEntry(NewArg(fileName), counter, index)
+}
+
+// PrintEntry prints the subtotals in the rows of "subtotal" class.
+func (hp *htmlPrinter) PrintEntry(entry NameAndIndex, counter Counter, index int) {
+	hp.printRow(countCells(entry.Name(), counter, hp.ct, hp.sizer), "td", recordKind(entry), nil)
+}
+
+// PrintError prints the error in the row of "error" class, and the message spans the columns of the counts.
+func (hp *htmlPrinter) PrintError(err *errors.Error, index int) {
+	fmt.Fprintf(hp.dest, `    <tr class="error"><td style="text-align:left">%s</td>`, html.EscapeString(err.Name))
+	fmt.Fprintf(hp.dest, `<td colspan="%d" style="text-align:left">%s</td></tr>`, len(headerCells(hp.ct))-1, html.EscapeString(err.Error()))
+	fmt.Fprintln(hp.dest)
+}
+
+func (hp *htmlPrinter) PrintTotal(rs *ResultSet) {
+	hp.totalPrinted = true
+	fmt.Fprintln(hp.dest, "  </tbody>")
+	fmt.Fprintln(hp.dest, "  <tfoot>")
+	hp.printRow(countCells(rs.total.Name(), rs.total, hp.ct, hp.sizer), "th", "", nil)
+	fmt.Fprintln(hp.dest, "  </tfoot>")
+}
+
+func (hp *htmlPrinter) PrintFooter() {
+	if !hp.totalPrinted {
+		fmt.Fprintln(hp.dest, "  </tbody>")
+	}
+	fmt.Fprintln(hp.dest, "</table>")
+	if hp.stats == nil {
+		return
+	}
+	for _, table := range statisticsTables(hp.stats, hp.sizer) {
+		hp.printTable(table)
+	}
+}
+
+func (hp *htmlPrinter) PrintStatistics(stats *Statistics) {
+	hp.stats = stats
+}
+
+func (hp *htmlPrinter) printTable(table *tableRows) {
+	fmt.Fprintln(hp.dest, "<table>")
+	fmt.Fprintln(hp.dest, "  <thead>")
+	hp.printRow(table.rows[0], "th", "", table)
+	fmt.Fprintln(hp.dest, "  </thead>")
+	fmt.Fprintln(hp.dest, "  <tbody>")
+	for _, row := range table.rows[1:] {
+		hp.printRow(row, "td", "", table)
+	}
+	fmt.Fprintln(hp.dest, "  </tbody>")
+	fmt.Fprintln(hp.dest, "</table>")
+}
+
+type latexPrinter struct {
+	dest   io.Writer
+	sizer  Sizer
+	ct     CounterType
+	table  *tableRows
+	hasSum bool
+	stats  *Statistics
+}
+
+var latexReplacer = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`&`, `\&`,
+	`%`, `\%`,
+	`$`, `\$`,
+	`#`, `\#`,
+	`_`, `\_`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`~`, `\textasciitilde{}`,
+	`^`, `\textasciicircum{}`,
+)
+
+func escapeLaTeX(from string) string {
+	return latexReplacer.Replace(from)
+}
+
+func (lp *latexPrinter) PrintHeader(ct CounterType) {
+	lp.ct = ct
+	lp.table = &tableRows{}
+	lp.table.append(headerCells(ct))
+}
+
+func (lp *latexPrinter) PrintEach(fileName string, counter Counter, index int) {
+	lp.PrintEntry(NewArg(fileName), counter, index)
+}
+
+// PrintEntry prints the subtotals in italic.
+func (lp *latexPrinter) PrintEntry(entry NameAndIndex, counter Counter, index int) {
+	cells := escapeCells(countCells(entry.Name(), counter, lp.ct, lp.sizer))
+	if IsSubtotal(entry) {
+		cells[0] = fmt.Sprintf(`\textit{%s}`, cells[0])
+	}
+	lp.table.append(cells)
+}
+
+// PrintError prints the error in the row whose message spans the columns of the counts.
+func (lp *latexPrinter) PrintError(err *errors.Error, index int) {
+	lp.table.appendSpan(escapeCells(errorCells(err.Name, err.Error(), lp.ct)))
+}
+
+func escapeCells(cells []string) []string {
+	results := []string{}
+	for _, cell := range cells {
+		results = append(results, escapeLaTeX(cell))
+	}
+	return results
+}
+
+func (lp *latexPrinter) PrintTotal(rs *ResultSet) {
+	lp.hasSum = true
+	lp.table.append(escapeCells(countCells(rs.total.Name(), rs.total, lp.ct, lp.sizer)))
+}
+
+func (lp *latexPrinter) PrintStatistics(stats *Statistics) {
+	lp.stats = stats
+}
+
+func (lp *latexPrinter) PrintFooter() {
+	lp.printTabular(lp.table, lp.hasSum)
+	if lp.stats == nil {
+		return
+	}
+	for _, table := range statisticsTables(lp.stats, lp.sizer) {
+		for index, row := range table.rows {
+			table.rows[index] = escapeCells(row)
+		}
+		fmt.Fprintln(lp.dest)
+		lp.printTabular(table, false)
+	}
+}
+
+func (lp *latexPrinter) printTabular(table *tableRows, hasSum bool) {
+	widths := table.widths()
+	columns := new(strings.Builder)
+	for i := range widths {
+		if table.isLeft(i) {
+			columns.WriteString("l")
+		} else {
+			columns.WriteString("r")
+		}
+	}
+	fmt.Fprintf(lp.dest, "\\begin{tabular}{%s}\n", columns.String())
+	fmt.Fprintln(lp.dest, `\hline`)
+	for index, row := range table.rows {
+		if index == len(table.rows)-1 && hasSum {
+			fmt.Fprintln(lp.dest, `\hline`)
+		}
+		if table.spans[index] {
+			lp.printSpan(row, widths)
+		} else {
+			lp.printRow(table, row, widths)
+		}
+		if index == 0 {
+			fmt.Fprintln(lp.dest, `\hline`)
+		}
+	}
+	fmt.Fprintln(lp.dest, `\hline`)
+	fmt.Fprintln(lp.dest, `\end{tabular}`)
+}
+
+func (lp *latexPrinter) printRow(table *tableRows, row []string, widths []int) {
+	cells := []string{}
+	for i, cell := range row {
+		cells = append(cells, table.align(cell, i, widths[i]))
+	}
+	fmt.Fprintf(lp.dest, "%s \\\\\n", strings.Join(cells, " & "))
+}
+
+func (lp *latexPrinter) printSpan(row []string, widths []int) {
+	fmt.Fprintf(lp.dest, "%s & \\multicolumn{%d}{l}{%s} \\\\\n", padRight(row[0], widths[0]), len(widths)-1, row[1])
+}
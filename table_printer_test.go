@@ -0,0 +1,103 @@
+package wildcat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownPrinter(t *testing.T) {
+	writer := new(strings.Builder)
+	rs := createResultSetForTest()
+	rs.Print(NewPrinter(writer, "markdown", &defaultSizer{}))
+	result := writer.String()
+	wont := `| file name                        | lines | words | characters | bytes |
+| :------------------------------- | ----: | ----: | ---------: | ----: |
+| testdata/wc/humpty_dumpty.txt    |     4 |    26 |        142 |   142 |
+| testdata/wc/ja/sakura_sakura.txt |    15 |    26 |        118 |   298 |
+| **total (2 entries)**            |    19 |    52 |        260 |   440 |
+`
+	if result != wont {
+		t.Errorf("the result by MarkdownPrinter did not match, wont %s, got %s", wont, result)
+	}
+}
+
+func TestHtmlPrinter(t *testing.T) {
+	writer := new(strings.Builder)
+	rs := createResultSetForTest()
+	rs.Print(NewPrinter(writer, "html", &defaultSizer{}))
+	result := writer.String()
+	if !strings.Contains(result, `<tr><td style="text-align:left">testdata/wc/humpty_dumpty.txt</td><td style="text-align:right">4</td><td style="text-align:right">26</td><td style="text-align:right">142</td><td style="text-align:right">142</td></tr>`) {
+		t.Errorf("the result by HtmlPrinter did not contains humpty_dumpty.txt, got %s", result)
+	}
+	if !strings.Contains(result, `<tfoot>
+    <tr><th style="text-align:left">total (2 entries)</th><th style="text-align:right">19</th><th style="text-align:right">52</th><th style="text-align:right">260</th><th style="text-align:right">440</th></tr>
+  </tfoot>`) {
+		t.Errorf("the result by HtmlPrinter did not contains total, got %s", result)
+	}
+	if strings.Count(result, "</tbody>") != 1 {
+		t.Errorf("the result by HtmlPrinter should close tbody once, got %s", result)
+	}
+}
+
+func TestLaTeXPrinter(t *testing.T) {
+	writer := new(strings.Builder)
+	rs := createResultSetForTest()
+	rs.Print(NewPrinter(writer, "latex", &defaultSizer{}))
+	result := writer.String()
+	wont := `\begin{tabular}{lrrrr}
+\hline
+file name                         & lines & words & characters & bytes \\
+\hline
+testdata/wc/humpty\_dumpty.txt    &     4 &    26 &        142 &   142 \\
+testdata/wc/ja/sakura\_sakura.txt &    15 &    26 &        118 &   298 \\
+\hline
+total (2 entries)                 &    19 &    52 &        260 &   440 \\
+\hline
+\end{tabular}
+`
+	if result != wont {
+		t.Errorf("the result by LaTeXPrinter did not match, wont %s, got %s", wont, result)
+	}
+}
+
+func TestEscapeInTablePrinters(t *testing.T) {
+	testdata := []struct {
+		give     string
+		escaper  func(string) string
+		wontText string
+	}{
+		{"a|b", escapeMarkdown, `a\|b`},
+		{"100%_{x}", escapeLaTeX, `100\%\_\{x\}`},
+	}
+	for _, td := range testdata {
+		got := td.escaper(td.give)
+		if got != td.wontText {
+			t.Errorf("escape(%s) did not match, wont %s, got %s", td.give, td.wontText, got)
+		}
+	}
+}
+
+func TestSubtotalsAndErrorsInTablePrinters(t *testing.T) {
+	testdata := []struct {
+		giveFormat   string
+		wontSubtotal string
+		wontError    string
+	}{
+		{"markdown", `| *testdata/wc/ja*`, `| testdata/not_exist.txt           | error: testdata/not_exist.txt: file or directory not found |`},
+		{"html", `<tr class="subtotal"><td style="text-align:left">testdata/wc/ja</td>`, `<tr class="error"><td style="text-align:left">testdata/not_exist.txt</td><td colspan="4" style="text-align:left">testdata/not_exist.txt: file or directory not found</td></tr>`},
+		{"latex", `\textit{testdata/wc/ja}`, `& \multicolumn{4}{l}{testdata/not\_exist.txt: file or directory not found} \\`},
+	}
+	rs := countForRollupTest("testdata/wc/ja", "testdata/not_exist.txt")
+	rs.SetRollup(&Rollup{Enabled: true})
+	for _, td := range testdata {
+		writer := new(strings.Builder)
+		rs.Print(NewPrinter(writer, td.giveFormat, &defaultSizer{}))
+		result := writer.String()
+		if !strings.Contains(result, td.wontSubtotal) {
+			t.Errorf("%s: subtotal row did not match, wont %s, got %s", td.giveFormat, td.wontSubtotal, result)
+		}
+		if !strings.Contains(result, td.wontError) {
+			t.Errorf("%s: error row did not match, wont %s, got %s", td.giveFormat, td.wontError, result)
+		}
+	}
+}
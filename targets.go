@@ -1,6 +1,10 @@
 package wildcat
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
+
 	"github.com/tamada/wildcat/errors"
 )
 
@@ -29,3 +33,48 @@ func (config *Config) updateIgnore(newIgnore Ignore) *Config {
 func (config *Config) IsIgnore(line string) bool {
 	return config.ignore != nil && config.ignore.IsIgnore(line)
 }
+
+// IsUnderRoot checks the given path is under ReadOptions.Root after resolving symbolic links.
+// The relative path is resolved against Root, the same as the targets for counting.
+// If Root is not specified, this method returns true, or false if ReadOptions.NoLocalFiles is set.
+func (config *Config) IsUnderRoot(path string) bool {
+	if config.readOpts.Root == "" {
+		return !config.readOpts.NoLocalFiles
+	}
+	return isUnder(resolvePath(config.readOpts.Root), resolvePath(config.localPath(path)))
+}
+
+// localPath returns the path on the local disk of the given name, which is relative to ReadOptions.Root.
+// The absolute names, and the names without Root are returned as they are.
+func (config *Config) localPath(name string) string {
+	if config.readOpts.Root == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(config.readOpts.Root, name)
+}
+
+// renamePathError replaces the path in the given error with the given name, for hiding the location of ReadOptions.Root.
+func renamePathError(err error, name string) error {
+	if pathErr, ok := err.(*os.PathError); ok {
+		return &os.PathError{Op: pathErr.Op, Path: name, Err: pathErr.Err}
+	}
+	return err
+}
+
+// resolvePath returns the absolute path of the given path with resolving symbolic links.
+// If the path does not exist, the absolute path without resolving is returned.
+func resolvePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
+
+func isUnder(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator))
+}
@@ -0,0 +1,121 @@
+package wildcat
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"text/template"
+
+	"github.com/dustin/go-humanize"
+)
+
+// TemplateEntry is the data given to the template of the template printer.
+// The template is executed for each entry, and for the total (IsTotal is true).
+type TemplateEntry struct {
+	Name       string
+	Order      string
+	Index      int
+	IsTotal    bool
+	EntryCount int64
+	Lines      int64
+	Words      int64
+	Characters int64
+	Bytes      int64
+}
+
+var templateFuncs = template.FuncMap{
+	"comma":    humanize.Comma,
+	"humanize": func(number int64) string { return humanize.Bytes(uint64(number)) },
+}
+
+type templatePrinter struct {
+	dest     io.Writer
+	template *template.Template
+	err      error
+}
+
+var templateEscapes = strings.NewReplacer(`\t`, "\t", `\n`, "\n")
+
+// ReadTemplate returns the template text of the given value.
+// If the given value is the path of existing file, this function returns the content of the file.
+// Otherwise, the escape sequences (\t and \n) in the given value are converted into tab and newline.
+func ReadTemplate(value string) (string, error) {
+	if !ExistFile(value) {
+		return templateEscapes.Replace(value), nil
+	}
+	data, err := ioutil.ReadFile(value)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", value, err)
+	}
+	return string(data), nil
+}
+
+// NewTemplatePrinter generates the printer for printing each result by the given template text (text/template).
+// The template accepts TemplateEntry as the data, and the newline is appended if the template result does not end with it.
+func NewTemplatePrinter(dest io.Writer, templateText string) (Printer, error) {
+	tmpl, err := template.New("wildcat").Funcs(templateFuncs).Parse(templateText)
+	if err != nil {
+		return nil, fmt.Errorf("template parse error: %w", err)
+	}
+	return &templatePrinter{dest: dest, template: tmpl}, nil
+}
+
+func newTemplateEntry(name string, counter Counter) *TemplateEntry {
+	return &TemplateEntry{
+		Name:       name,
+		Lines:      counter.Count(Lines),
+		Words:      counter.Count(Words),
+		Characters: counter.Count(Characters),
+		Bytes:      counter.Count(Bytes),
+	}
+}
+
+func (tp *templatePrinter) execute(entry *TemplateEntry) {
+	if tp.err != nil {
+		return
+	}
+	builder := new(strings.Builder)
+	if err := tp.template.Execute(builder, entry); err != nil {
+		tp.err = err
+		return
+	}
+	result := builder.String()
+	if !strings.HasSuffix(result, "\n") {
+		result = result + "\n"
+	}
+	fmt.Fprint(tp.dest, result)
+}
+
+func (tp *templatePrinter) PrintHeader(ct CounterType) {
+	// do nothing.
+}
+
+func (tp *templatePrinter) PrintEach(fileName string, counter Counter, index int) {
+	tp.PrintEntry(NewArg(fileName), counter, index)
+}
+
+func (tp *templatePrinter) PrintEntry(entry NameAndIndex, counter Counter, index int) {
+	data := newTemplateEntry(entry.Name(), counter)
+	data.Order = entry.Index().String()
+	data.Index = index
+	data.EntryCount = 1
+	tp.execute(data)
+}
+
+func (tp *templatePrinter) PrintTotal(rs *ResultSet) {
+	data := newTemplateEntry(rs.total.Name(), rs.total)
+	data.IsTotal = true
+	data.Index = rs.Size()
+	data.EntryCount = rs.total.entryCount
+	tp.execute(data)
+}
+
+func (tp *templatePrinter) PrintFooter() {
+	// do nothing.
+}
+
+// Err returns the first error occurred in executing the template.
+func (tp *templatePrinter) Err() error {
+	return tp.err
+}
@@ -0,0 +1,44 @@
+package wildcat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplatePrinter(t *testing.T) {
+	testdata := []struct {
+		giveTemplate string
+		wontResult   string
+	}{
+		{`{{.Lines}}\t{{.Name}}`, "4\ttestdata/wc/humpty_dumpty.txt\n15\ttestdata/wc/ja/sakura_sakura.txt\n19\ttotal (2 entries)\n"},
+		{`{{if not .IsTotal}}{{.Order}},{{.Index}},{{comma .Bytes}}\n{{end}}`, "0,0,142\n1,1,298\n\n"},
+		{`{{if .IsTotal}}{{.EntryCount}} {{humanize .Bytes}}{{end}}`, "\n\n2 440 B\n"},
+	}
+	for _, td := range testdata {
+		text, _ := ReadTemplate(td.giveTemplate)
+		writer := new(strings.Builder)
+		printer, err := NewTemplatePrinter(writer, text)
+		if err != nil {
+			t.Errorf("%s: parse failed: %s", td.giveTemplate, err.Error())
+			continue
+		}
+		rs := createResultSetForTest()
+		if err := rs.Print(printer); err != nil {
+			t.Errorf("%s: print failed: %s", td.giveTemplate, err.Error())
+		}
+		if writer.String() != td.wontResult {
+			t.Errorf("%s: result did not match, wont %q, got %q", td.giveTemplate, td.wontResult, writer.String())
+		}
+	}
+}
+
+func TestTemplatePrinterErrors(t *testing.T) {
+	if _, err := NewTemplatePrinter(new(strings.Builder), "{{.Lines"); err == nil {
+		t.Errorf("invalid template was parsed")
+	}
+	printer, _ := NewTemplatePrinter(new(strings.Builder), "{{.Unknown}}")
+	rs := createResultSetForTest()
+	if err := rs.Print(printer); err == nil {
+		t.Errorf("unknown field should be the error")
+	}
+}
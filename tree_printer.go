@@ -0,0 +1,135 @@
+package wildcat
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type treePrinter struct {
+	dest     io.Writer
+	sizer    Sizer
+	ct       CounterType
+	table    *tableRows
+	depths   []int
+	parents  []string
+	hasTotal bool
+	stats    *Statistics
+}
+
+func (tp *treePrinter) requiresRollup() bool {
+	return true
+}
+
+func (tp *treePrinter) PrintHeader(ct CounterType) {
+	tp.ct = ct
+	tp.table = &tableRows{}
+	tp.table.append(headerCells(ct))
+	tp.depths = []int{0}
+	tp.parents = []string{}
+}
+
+// relativeName trims the name of the parent from the given name.
+func relativeName(name, parent string) string {
+	if parent == "" || !strings.HasPrefix(name, parent) || len(name) == len(parent) {
+		return name
+	}
+	return strings.TrimLeft(name[len(parent):], "/\\!")
+}
+
+func (tp *treePrinter) PrintEach(fileName string, counter Counter, index int) {
+	tp.PrintEntry(NewArg(fileName), counter, index)
+}
+
+func (tp *treePrinter) PrintEntry(entry NameAndIndex, counter Counter, index int) {
+	depth := entry.Index().depth()
+	parent := ""
+	if depth > 1 && depth-2 < len(tp.parents) {
+		parent = tp.parents[depth-2]
+	}
+	if depth-1 < len(tp.parents) {
+		tp.parents = tp.parents[:depth-1]
+	}
+	tp.parents = append(tp.parents, entry.Name())
+	tp.table.append(countCells(relativeName(entry.Name(), parent), counter, tp.ct, tp.sizer))
+	tp.depths = append(tp.depths, depth)
+}
+
+func (tp *treePrinter) PrintTotal(rs *ResultSet) {
+	tp.hasTotal = true
+	tp.table.append(countCells(rs.total.Name(), rs.total, tp.ct, tp.sizer))
+	tp.depths = append(tp.depths, 0)
+}
+
+// findLasts finds the rows which are the last one in their siblings.
+func findLasts(depths []int) []bool {
+	lasts := make([]bool, len(depths))
+	seen := map[int]bool{}
+	for i := len(depths) - 1; i >= 0; i-- {
+		lasts[i] = !seen[depths[i]]
+		seen[depths[i]] = true
+		for depth := range seen {
+			if depth > depths[i] {
+				delete(seen, depth)
+			}
+		}
+	}
+	return lasts
+}
+
+func treePrefix(depth int, last bool, ancestors map[int]bool) string {
+	if depth <= 1 {
+		return ""
+	}
+	prefix := new(strings.Builder)
+	for level := 2; level < depth; level++ {
+		if ancestors[level] {
+			prefix.WriteString("    ")
+		} else {
+			prefix.WriteString("│   ")
+		}
+	}
+	if last {
+		prefix.WriteString("└── ")
+	} else {
+		prefix.WriteString("├── ")
+	}
+	return prefix.String()
+}
+
+func (tp *treePrinter) PrintFooter() {
+	widths := tp.table.widths()
+	bodies := tp.depths[1:]
+	if tp.hasTotal {
+		bodies = bodies[:len(bodies)-1]
+	}
+	lasts := findLasts(bodies)
+	ancestors := map[int]bool{}
+	for index, row := range tp.table.rows {
+		prefix := ""
+		if index > 0 && index <= len(bodies) {
+			depth := bodies[index-1]
+			prefix = treePrefix(depth, lasts[index-1], ancestors)
+			ancestors[depth] = lasts[index-1]
+		}
+		tp.printRow(row, prefix, widths, index == 0)
+	}
+	if tp.stats != nil {
+		printPlainStatistics(tp.dest, tp.stats, tp.sizer, false)
+	}
+}
+
+func (tp *treePrinter) PrintStatistics(stats *Statistics) {
+	tp.stats = stats
+}
+
+func (tp *treePrinter) printRow(row []string, prefix string, widths []int, header bool) {
+	line := new(strings.Builder)
+	for i, count := range row[1:] {
+		fmt.Fprintf(line, " %s", padLeft(count, widths[i+1]))
+	}
+	if !header {
+		fmt.Fprintf(line, " %s%s", prefix, row[0])
+	}
+	fmt.Fprintln(tp.dest, line.String())
+}
@@ -2,86 +2,240 @@ package wildcat
 
 import (
 	"bufio"
+	"context"
+	stderrors "errors"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/tamada/wildcat/errors"
 )
 
 // Wildcat is the struct treating to count the specified files, directories, and urls.
+// The targets are counted by the worker pool of RuntimeOptions.ThreadNumber workers (zero or less means runtime.GOMAXPROCS),
+// and listing directories and reading file lists are also the tasks of the pool, so that walking and counting overlap.
+// The counting is cancelled when the number of errors reaches RuntimeOptions.MaxErrors (or the first error with RuntimeOptions.FailFast).
+// Each counting runs in its own session, therefore, Wildcat is reusable, and safe for concurrent use by multiple goroutines.
 type Wildcat struct {
 	config     *Config
 	eitherChan chan *Either
 	generator  Generator
 	progress   Progress
+	pool       *workerPool
+	worker     bool
+	ctx        context.Context
+	cancel     context.CancelFunc
 }
 
+// countTask is the task run by the workers, the given session is the receiver running in the worker.
+type countTask func(session *Wildcat) *Either
+
 // NewWildcat creates an instance of Wildcat.
 func NewWildcat(opts *ReadOptions, runtimeOpts *RuntimeOptions, generator Generator) *Wildcat {
 	channel := make(chan *Either)
 	return &Wildcat{
-		config:     NewConfig(ignores(".", !opts.NoIgnore, nil), opts, runtimeOpts, errors.New()),
+		config:     NewConfig(ignores(".", !opts.NoIgnore, nil), opts, runtimeOpts, errors.NewWithLimit(runtimeOpts.maxErrors())),
 		eitherChan: channel,
 		generator:  generator,
-		progress:   NewProgress(runtimeOpts.ShowProgress, runtimeOpts.ThreadNumber),
+		ctx:        context.Background(),
+		cancel:     func() {},
 	}
 }
 
-func (wc *Wildcat) run(f func(Generator, *Config) *Either) {
-	wc.progress.UpdateTarget()
-	go func() {
+// start creates the session for a counting, which has its own channel, progress, errors, and workers.
+// The context of the session is cancelled when the given parent is done, or the errors reach the limit.
+func (wc *Wildcat) start(parent context.Context) *Wildcat {
+	ec := errors.NewWithLimit(wc.config.runtimeOpts.maxErrors())
+	ctx, cancel := context.WithCancel(parent)
+	session := &Wildcat{
+		config:     NewConfig(wc.config.ignore, wc.config.readOpts, wc.config.runtimeOpts, ec),
+		eitherChan: make(chan *Either),
+		generator:  wc.generator,
+		progress:   wc.config.runtimeOpts.newProgress(),
+		pool:       newWorkerPool(workerSize(wc.config.runtimeOpts.ThreadNumber)),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+	go cancelOnExceeded(ctx, cancel, ec)
+	return session
+}
+
+// cancelOnExceeded cancels the counting when the given error center reaches its limit.
+func cancelOnExceeded(ctx context.Context, cancel context.CancelFunc, ec *errors.Center) {
+	select {
+	case <-ec.Exceeded():
+		cancel()
+	case <-ctx.Done():
+	}
+}
+
+// isCancelled reports whether the counting was cancelled.
+func (wc *Wildcat) isCancelled() bool {
+	return wc.ctx.Err() != nil
+}
+
+// run submits the given task to the worker pool, and the worker sends the result of the task to the receiver.
+// The producer blocks while the work queue is full, and the workers never block (see workerPool).
+// This method reports whether the task was submitted, the task is not submitted after the counting was cancelled.
+func (wc *Wildcat) run(f countTask) bool {
+	if err := updateTarget(wc.ctx, wc.progress); err != nil {
+		return false
+	}
+	task := func() {
 		defer wc.progress.Done()
-		either := f(wc.generator, wc.config)
-		wc.eitherChan <- either
-	}()
+		wc.eitherChan <- wc.inWorker().execute(f)
+	}
+	if wc.worker {
+		wc.pool.submitFromWorker(task)
+	} else {
+		wc.pool.submit(task)
+	}
+	return true
 }
 
-func (wc *Wildcat) CountEntries(entries []Entry) (*ResultSet, *errors.Center) {
-	for _, entry := range entries {
-		e := entry
-		err := wc.handleItem(e)
-		wc.config.ec.Push(err)
+// runAndWait submits the given task, and waits for it to finish, or the counting to be cancelled.
+func (wc *Wildcat) runAndWait(f countTask) {
+	done := make(chan struct{})
+	submitted := wc.run(func(session *Wildcat) *Either {
+		defer close(done)
+		return f(session)
+	})
+	if submitted {
+		select {
+		case <-done:
+		case <-wc.ctx.Done():
+		}
 	}
-	go func() {
-		wc.progress.Wait()
-		wc.Close()
-	}()
-	return wc.receiveImpl()
+}
+
+// execute runs the given task unless the counting was cancelled.
+func (wc *Wildcat) execute(f countTask) *Either {
+	if wc.isCancelled() {
+		return &Either{Results: []*Result{}}
+	}
+	return f(wc)
+}
+
+// inWorker returns the copy of the receiver for running the tasks in a worker.
+func (wc *Wildcat) inWorker() *Wildcat {
+	if wc.worker {
+		return wc
+	}
+	session := *wc
+	session.worker = true
+	return &session
+}
+
+// CountEntries counts the given entries.
+func (wc *Wildcat) CountEntries(entries []Entry) (*ResultSet, *errors.Center) {
+	return wc.CountEntriesContext(context.Background(), entries)
+}
+
+// CountEntriesContext counts the given entries until the given context is done.
+// If the context is done, this method returns the results counted before it, and the errors contain the error of the context.
+func (wc *Wildcat) CountEntriesContext(ctx context.Context, entries []Entry) (*ResultSet, *errors.Center) {
+	return wc.count(ctx, func(session *Wildcat) {
+		for _, entry := range entries {
+			if session.isCancelled() {
+				break
+			}
+			err := session.handleItem(entry)
+			session.config.ec.Push(err)
+		}
+	})
+}
+
+// CountStreamContext counts the entries returned by the given next function one by one, until next returns io.EOF.
+// The entries of a stream (e.g., the parts of a multipart body) are available only after the previous one is consumed,
+// therefore, each entry is counted before calling next again, and the results keep the order of the stream.
+// The error from next other than io.EOF stops the counting, and is contained in the returned errors.
+func (wc *Wildcat) CountStreamContext(ctx context.Context, next func() (Entry, error)) (*ResultSet, *errors.Center) {
+	return wc.count(ctx, func(session *Wildcat) {
+		for !session.isCancelled() {
+			entry, err := next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				session.config.ec.Push(err)
+				break
+			}
+			session.handleEntryNow(entry)
+		}
+	})
 }
 
 // CountAll counts the arguments in the given Argf.
 func (wc *Wildcat) CountAll(argf *Argf) (*ResultSet, *errors.Center) {
-	wc.progress.UpdateTarget()
-	go func() {
+	return wc.CountAllContext(context.Background(), argf)
+}
+
+// CountAllContext counts the arguments in the given Argf until the given context is done.
+// The cancellation and the deadline of the context are honoured in walking directories, reading file lists,
+// extracting archives, fetching urls, and waiting for the threads.
+// If the context is done, this method returns the results counted before it, and the errors contain the error of the context
+// (context.Canceled or context.DeadlineExceeded, errors.Is works with the returned Center).
+func (wc *Wildcat) CountAllContext(ctx context.Context, argf *Argf) (*ResultSet, *errors.Center) {
+	return wc.count(ctx, func(session *Wildcat) {
 		for _, arg := range argf.Arguments {
-			err := wc.handleItem(arg)
-			wc.config.ec.Push(err)
+			if session.isCancelled() {
+				break
+			}
+			err := session.handleItem(arg)
+			session.config.ec.Push(err)
 		}
 		if len(argf.Arguments) == 0 {
-			wc.handleEntry(&stdinEntry{index: NewOrder()})
+			session.handleEntry(&stdinEntry{index: NewOrder()})
 		}
-		wc.progress.Done()
-	}()
+	})
+}
+
+// count runs the given producer in a new session, which submits the targets, and receives the results until all of the tasks are done.
+// The producer runs in its own goroutine while the receiver runs in the caller, so the workers are never blocked by sending results.
+// The producer is also a target of the progress, so waiting for the tasks never finishes before the producer submits all of the targets.
+func (wc *Wildcat) count(parent context.Context, producer func(session *Wildcat)) (*ResultSet, *errors.Center) {
+	session := wc.start(parent)
+	if err := updateTarget(session.ctx, session.progress); err == nil {
+		go func() {
+			defer session.progress.Done()
+			producer(session)
+		}()
+	}
 	go func() {
-		wc.progress.Wait()
-		wc.Close()
+		session.progress.Wait()
+		session.Close()
 	}()
-	return wc.receiveImpl()
+	return session.receiveImpl(parent)
 }
 
-func (wc *Wildcat) receiveImpl() (*ResultSet, *errors.Center) {
+// receiveImpl receives the results until all of targets are done.
+// The errors caused by the cancellation are replaced with the error of the given parent context.
+func (wc *Wildcat) receiveImpl(parent context.Context) (*ResultSet, *errors.Center) {
 	rs := NewResultSet()
 	for either := range wc.eitherChan {
+		if wc.isCancelled() && isCancellation(either.Err) {
+			either.Err = nil
+		}
 		receiveEither(either, rs, wc.config.ec)
 	}
+	wc.config.ec.Push(parent.Err())
+	rs.errs = wc.config.ec.Errors()
+	wc.cancel()
 	return rs, wc.config.ec
 }
 
+func isCancellation(err error) bool {
+	return err != nil && (stderrors.Is(err, context.Canceled) || stderrors.Is(err, context.DeadlineExceeded))
+}
+
 // Close finishes the receiver object.
 func (wc *Wildcat) Close() {
+	if wc.pool != nil {
+		wc.pool.close()
+	}
 	close(wc.eitherChan)
 }
 
@@ -91,14 +245,18 @@ func (wc *Wildcat) updateFileList(fileList bool) *Wildcat {
 	return wc.updateOpts(&newOpts)
 }
 
-// ReadFileListFromReader reads data from the given reader as the file list.
-func (wc *Wildcat) ReadFileListFromReader(in io.Reader, index *Order) {
+// readFileList reads data from the given reader as the file list, and submits the listed entries.
+// This method must be called in a counting session, which has the progress and the worker pool.
+func (wc *Wildcat) readFileList(in io.Reader, index *Order) {
 	reader := bufio.NewReader(in)
 	order := index.Sub()
 	newWc := wc.updateFileList(false)
 	for {
 		line, err := reader.ReadString('\n')
 		line = strings.TrimSpace(line)
+		if wc.isCancelled() {
+			break
+		}
 		if line != "" && !newWc.config.IsIgnore(line) {
 			err := newWc.handleItem(NewArgWithIndex(order, line))
 			newWc.config.ec.Push(err)
@@ -110,15 +268,44 @@ func (wc *Wildcat) ReadFileListFromReader(in io.Reader, index *Order) {
 	}
 }
 
+// dirBatchSize is the number of entries read from a directory at once.
+// The entries are sorted by their names in each batch, therefore, the entries of the directories smaller than it are in the order of names.
+const dirBatchSize = 1024
+
+// handleDir lists the given directory, and submits its entries.  This method runs as a task of the worker pool.
+// The directory is read incrementally by dirBatchSize entries for bounding the memory for the huge directories.
 func (wc *Wildcat) handleDir(arg NameAndIndex) *Either {
-	currentIgnore := ignores(arg.Name(), !wc.config.readOpts.NoIgnore, wc.config.ignore)
-	fileInfos, err := ioutil.ReadDir(arg.Name())
+	path := wc.config.localPath(arg.Name())
+	dir, err := os.Open(path)
 	if err != nil {
-		return &Either{Err: err}
+		return &Either{Err: wrapError("readdir", arg, renamePathError(err, arg.Name()))}
 	}
+	defer dir.Close()
+	currentIgnore := ignores(path, !wc.config.readOpts.NoIgnore, wc.config.ignore)
 	index := arg.Index().Sub()
+	for !wc.isCancelled() {
+		fileInfos, err := dir.Readdir(dirBatchSize)
+		sort.Slice(fileInfos, func(i, j int) bool {
+			return fileInfos[i].Name() < fileInfos[j].Name()
+		})
+		index = wc.handleDirEntries(arg.Name(), fileInfos, currentIgnore, index)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return &Either{Err: wrapError("readdir", arg, renamePathError(err, arg.Name())), Groups: []NameAndIndex{arg}}
+		}
+	}
+	return &Either{Results: []*Result{}, Groups: []NameAndIndex{arg}}
+}
+
+// handleDirEntries submits the given entries of the directory, and returns the index of the next entry.
+func (wc *Wildcat) handleDirEntries(dirName string, fileInfos []os.FileInfo, currentIgnore Ignore, index *Order) *Order {
 	for _, info := range fileInfos {
-		newName := filepath.Join(arg.Name(), info.Name())
+		if wc.isCancelled() {
+			break
+		}
+		newName := filepath.Join(dirName, info.Name())
 		if !isIgnore(wc.config.readOpts, currentIgnore, newName) {
 			newWc := wc.updateIgnore(currentIgnore)
 			err := newWc.handleItem(NewArgWithIndex(index, newName))
@@ -126,59 +313,88 @@ func (wc *Wildcat) handleDir(arg NameAndIndex) *Either {
 			index = index.Next()
 		}
 	}
-	return &Either{Results: []*Result{}}
+	return index
 }
 
+// handleEntryAsFileList reads the given entry as the file list, and submits the listed entries.
+// This method runs as a task of the worker pool.
 func (wc *Wildcat) handleEntryAsFileList(entry Entry) *Either {
 	reader, err := entry.Open()
 	if err != nil {
-		return &Either{Err: err}
+		return &Either{Err: wrapError("open", entry, err)}
 	}
 	defer reader.Close()
-	wc.ReadFileListFromReader(reader, entry.Index())
-	return &Either{Results: []*Result{}}
+	wc.readFileList(reader, entry.Index())
+	return &Either{Results: []*Result{}, Groups: []NameAndIndex{entry}}
 }
 
-func (wc *Wildcat) handleEntry(entry Entry) *Either {
-	targetEntry := entry
-	if !wc.config.readOpts.NoExtract {
-		newEntry, _ := ConvertToArchiveEntry(entry)
-		targetEntry = newEntry
-	}
-	if wc.config.readOpts.FileList {
-		return wc.handleEntryAsFileList(targetEntry)
+// handleEntry submits the task for the given entry.
+func (wc *Wildcat) handleEntry(entry Entry) {
+	wc.run(wc.entryTask(entry))
+}
+
+// handleEntryNow counts the given entry by a worker, and waits for it, since the entries of a stream must be consumed in order.
+func (wc *Wildcat) handleEntryNow(entry Entry) {
+	wc.runAndWait(wc.entryTask(entry))
+}
+
+// entryTask returns the task which converts the given entry into the archive entry (which may fetch the url),
+// and counts it, or reads it as the file list.
+func (wc *Wildcat) entryTask(entry Entry) countTask {
+	return func(session *Wildcat) *Either {
+		targetEntry := entry
+		if !session.config.readOpts.NoExtract {
+			newEntry, _ := convertToArchiveEntry(entry, session.config.readOpts.MaxExpansionSize)
+			targetEntry = newEntry
+		}
+		if session.config.readOpts.FileList {
+			return session.handleEntryAsFileList(targetEntry)
+		}
+		either := countEntry(session.ctx, targetEntry, session.generator)
+		either.Err = wrapError("count", targetEntry, either.Err)
+		return either
 	}
-	wc.run(func(arg1 Generator, arg2 *Config) *Either {
-		return targetEntry.Count(wc.generator)
-	})
-	return &Either{Results: []*Result{}}
 }
 
 func (wc *Wildcat) handleItem(oldArg NameAndIndex) error {
 	arg := NormalizePath(oldArg)
 	name := arg.Name()
+	path := wc.config.localPath(name)
 	entry, ok := arg.(Entry)
 	switch {
 	case ok:
 		wc.handleEntry(entry)
 	case IsURL(name):
-		wc.handleEntry(toURLEntry(arg, wc.config.runtimeOpts))
-	case ExistDir(name):
-		wc.handleDir(arg)
-	case ExistFile(name):
-		wc.handleEntry(NewFileEntryWithIndex(arg))
+		wc.handleEntry(toURLEntry(wc.ctx, arg, wc.config.runtimeOpts))
+	case !wc.config.IsUnderRoot(name):
+		return errors.NewError(errors.Permission, "stat", name, arg.Index().String(), fmt.Errorf("%s: outside of the root directory", name))
+	case ExistDir(path):
+		wc.run(func(session *Wildcat) *Either {
+			return session.handleDir(arg)
+		})
+	case ExistFile(path):
+		wc.handleEntry(&FileEntry{nai: arg, path: path})
 	default:
-		return fmt.Errorf("%s: file or directory not found", name)
+		return errors.NewError(errors.NotFound, "stat", name, arg.Index().String(), fmt.Errorf("%s: file or directory not found", name))
 	}
 	return nil
 }
 
+// wrapError wraps the given error with the operation, the name, and the order of the given entry.
+func wrapError(op string, entry NameAndIndex, err error) error {
+	return errors.Wrap(op, entry.Name(), entry.Index().String(), err)
+}
+
 func (wc *Wildcat) updateIgnore(newIgnore Ignore) *Wildcat {
 	return &Wildcat{
 		config:     wc.config.updateIgnore(newIgnore),
 		eitherChan: wc.eitherChan,
 		generator:  wc.generator,
 		progress:   wc.progress,
+		pool:       wc.pool,
+		worker:     wc.worker,
+		ctx:        wc.ctx,
+		cancel:     wc.cancel,
 	}
 }
 
@@ -188,15 +404,20 @@ func (wc *Wildcat) updateOpts(newOpts *ReadOptions) *Wildcat {
 		eitherChan: wc.eitherChan,
 		generator:  wc.generator,
 		progress:   wc.progress,
+		pool:       wc.pool,
+		worker:     wc.worker,
+		ctx:        wc.ctx,
+		cancel:     wc.cancel,
 	}
 }
 
+// receiveEither stores the results and the errors in the given either, the results may be partial if it has the error.
 func receiveEither(either *Either, rs *ResultSet, ec *errors.Center) {
-	if either.Err != nil {
-		ec.Push(either.Err)
-	} else {
-		for _, result := range either.Results {
-			rs.Push(result)
-		}
+	ec.Push(either.Err)
+	for _, result := range either.Results {
+		rs.Push(result)
+	}
+	for _, group := range either.Groups {
+		rs.pushGroup(group)
 	}
 }
@@ -1,6 +1,19 @@
 package wildcat
 
-import "testing"
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tamada/wildcat/errors"
+)
 
 func opts(fileList, noIgnore, noExtract, storeContent bool) *testOpts {
 	return &testOpts{
@@ -40,3 +53,264 @@ func TestBasic(t *testing.T) {
 		}
 	}
 }
+
+func TestMaxErrors(t *testing.T) {
+	testdata := []struct {
+		giveOpts      *RuntimeOptions
+		wontErrorSize int
+		wontExceeded  bool
+	}{
+		{&RuntimeOptions{ThreadNumber: 10}, 5, false},
+		{&RuntimeOptions{ThreadNumber: 10, FailFast: true}, 1, true},
+		{&RuntimeOptions{ThreadNumber: 10, MaxErrors: 3}, 3, true},
+		{&RuntimeOptions{ThreadNumber: 10, MaxErrors: 10}, 5, false},
+	}
+	args := []string{"not_exist1", "not_exist2", "not_exist3", "not_exist4", "not_exist5", "testdata/wc"}
+	for _, td := range testdata {
+		argf := NewArgf(args, &ReadOptions{}, td.giveOpts)
+		wc := NewWildcat(argf.Options, td.giveOpts, DefaultGenerator)
+		_, ec := wc.CountAll(argf)
+		if ec.Size() != td.wontErrorSize {
+			t.Errorf("%v: error size did not match, wont %d, got %d", td.giveOpts, td.wontErrorSize, ec.Size())
+		}
+		if ec.IsExceeded() != td.wontExceeded {
+			t.Errorf("%v: exceeded did not match, wont %v, got %v", td.giveOpts, td.wontExceeded, ec.IsExceeded())
+		}
+		if _, ec2 := wc.CountAll(argf); ec2.Size() != td.wontErrorSize {
+			t.Errorf("%v: error size of the second counting did not match, wont %d, got %d", td.giveOpts, td.wontErrorSize, ec2.Size())
+		}
+	}
+}
+
+func TestCountAllContext(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel2 := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel2()
+	testdata := []struct {
+		giveContext    context.Context
+		wontResultSize int
+		wontError      error
+	}{
+		{context.Background(), 3, nil},
+		{cancelled, 0, context.Canceled},
+		{expired, 0, context.DeadlineExceeded},
+	}
+	for _, td := range testdata {
+		argf := NewArgf([]string{"testdata/wc"}, &ReadOptions{}, &RuntimeOptions{ThreadNumber: 10})
+		rs, ec := NewWildcat(argf.Options, argf.RuntimeOpts, DefaultGenerator).CountAllContext(td.giveContext, argf)
+		if rs.Size() != td.wontResultSize {
+			t.Errorf("%v: result size did not match, wont %d, got %d", td.wontError, td.wontResultSize, rs.Size())
+		}
+		if td.wontError == nil && !ec.IsEmpty() {
+			t.Errorf("wont no errors, got %v", ec)
+		}
+		if td.wontError != nil && (!stderrors.Is(ec, td.wontError) || ec.Size() != 1) {
+			t.Errorf("wont only %v, got %v", td.wontError, ec)
+		}
+	}
+}
+
+func TestCountArchiveContext(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	entry, ok := ConvertToArchiveEntry(NewFileEntry("testdata/archives/wc.zip"))
+	if !ok {
+		t.Fatalf("testdata/archives/wc.zip should be the archive")
+	}
+	either := entry.(ContextEntry).CountContext(cancelled, DefaultGenerator)
+	if !stderrors.Is(either.Err, context.Canceled) || len(either.Results) != 0 {
+		t.Errorf("counting archive should be cancelled, got %v, %d results", either.Err, len(either.Results))
+	}
+}
+
+func createManyFiles(t *testing.T, size int) (string, []string) {
+	dir := t.TempDir()
+	names := []string{}
+	for i := 0; i < size; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("file%04d.txt", i))
+		if err := ioutil.WriteFile(name, []byte("hello world\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	return dir, names
+}
+
+// countWithTimeout runs the given counting, and fails the test if it does not finish in time (deadlock).
+func countWithTimeout(t *testing.T, count func() (*ResultSet, *errors.Center)) *ResultSet {
+	done := make(chan *ResultSet)
+	go func() {
+		rs, _ := count()
+		done <- rs
+	}()
+	select {
+	case rs := <-done:
+		return rs
+	case <-time.After(10 * time.Second):
+		t.Fatalf("counting did not finish in time")
+		return nil
+	}
+}
+
+func TestCountWithFewThreads(t *testing.T) {
+	dir, names := createManyFiles(t, 300)
+	listFile := filepath.Join(t.TempDir(), "list.txt")
+	ioutil.WriteFile(listFile, []byte(strings.Join(names, "\n")), 0644)
+	testdata := []struct {
+		giveArgs     []string
+		giveFileList bool
+		giveEntries  bool
+	}{
+		{names, false, true},
+		{names, false, false},
+		{[]string{dir}, false, false},
+		{[]string{listFile}, true, false},
+	}
+	for _, threads := range []int64{1, 2, 0} {
+		for _, td := range testdata {
+			readOpts := &ReadOptions{FileList: td.giveFileList}
+			runtimeOpts := &RuntimeOptions{ThreadNumber: threads}
+			wc := NewWildcat(readOpts, runtimeOpts, DefaultGenerator)
+			rs := countWithTimeout(t, func() (*ResultSet, *errors.Center) {
+				if td.giveEntries {
+					entries := []Entry{}
+					for _, name := range td.giveArgs {
+						entries = append(entries, NewFileEntry(name))
+					}
+					return wc.CountEntries(entries)
+				}
+				return wc.CountAll(NewArgf(td.giveArgs, readOpts, runtimeOpts))
+			})
+			if rs.Size() != len(names) || rs.total.lines != int64(len(names)) {
+				t.Errorf("threads %d, args %d, entries %v: result size did not match, wont %d, got %d (%d lines)",
+					threads, len(td.giveArgs), td.giveEntries, len(names), rs.Size(), rs.total.lines)
+			}
+		}
+	}
+}
+
+func TestCountTwice(t *testing.T) {
+	wc := NewWildcat(&ReadOptions{}, &RuntimeOptions{ThreadNumber: 1}, DefaultGenerator)
+	for i := 0; i < 2; i++ {
+		rs := countWithTimeout(t, func() (*ResultSet, *errors.Center) {
+			return wc.CountEntries([]Entry{NewFileEntry("testdata/wc/humpty_dumpty.txt"), NewFileEntry("not_exist.txt")})
+		})
+		if rs.Size() != 1 || len(rs.Errors()) != 1 {
+			t.Errorf("count %d: wont 1 result and 1 error, got %d results and %v", i, rs.Size(), rs.Errors())
+		}
+	}
+}
+
+func TestCountUnderRoot(t *testing.T) {
+	root, outside := t.TempDir(), t.TempDir()
+	ioutil.WriteFile(filepath.Join(root, "inside.txt"), []byte("inside\n"), 0644)
+	ioutil.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret\n"), 0644)
+	if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(root, "link.txt")); err != nil {
+		t.Skipf("symbolic link is not available: %v", err)
+	}
+	testdata := []struct {
+		giveArgs       []string
+		wontResultSize int
+		wontErrorSize  int
+	}{
+		{[]string{root}, 1, 1},
+		{[]string{filepath.Join(root, "inside.txt")}, 1, 0},
+		{[]string{filepath.Join(root, "..", filepath.Base(outside), "secret.txt")}, 0, 1},
+		{[]string{filepath.Join(root, "link.txt")}, 0, 1},
+		{[]string{"inside.txt"}, 1, 0},
+		{[]string{"../" + filepath.Base(outside) + "/secret.txt"}, 0, 1},
+		{[]string{"link.txt"}, 0, 1},
+	}
+	for _, td := range testdata {
+		readOpts := &ReadOptions{Root: root}
+		argf := NewArgf(td.giveArgs, readOpts, &RuntimeOptions{})
+		rs, ec := NewWildcat(readOpts, argf.RuntimeOpts, DefaultGenerator).CountAll(argf)
+		if rs.Size() != td.wontResultSize || ec.Size() != td.wontErrorSize {
+			t.Errorf("%v: wont %d results and %d errors, got %d results and %v", td.giveArgs, td.wontResultSize, td.wontErrorSize, rs.Size(), ec)
+		}
+		if td.wontErrorSize > 0 && !stderrors.Is(ec, errors.ErrPermission) {
+			t.Errorf("%v: wont permission error, got %v", td.giveArgs, ec)
+		}
+	}
+}
+
+func TestNamesRelativeToRoot(t *testing.T) {
+	root := t.TempDir()
+	os.Mkdir(filepath.Join(root, "sub"), 0755)
+	ioutil.WriteFile(filepath.Join(root, "sub", "inside.txt"), []byte("inside\n"), 0644)
+	ioutil.WriteFile(filepath.Join(root, "files.txt"), []byte("sub/inside.txt\nnot_exist.txt\n"), 0644)
+	testdata := []struct {
+		giveArgs     []string
+		giveFileList bool
+		wontName     string
+	}{
+		{[]string{"sub"}, false, "sub/inside.txt"},
+		{[]string{"files.txt"}, true, "sub/inside.txt"},
+	}
+	for _, td := range testdata {
+		readOpts := &ReadOptions{Root: root, FileList: td.giveFileList}
+		argf := NewArgf(td.giveArgs, readOpts, &RuntimeOptions{})
+		rs, ec := NewWildcat(readOpts, argf.RuntimeOpts, DefaultGenerator).CountAll(argf)
+		if rs.Size() != 1 || rs.Counter(td.wontName) == nil {
+			t.Errorf("%v: wont the result of %s, got %d results", td.giveArgs, td.wontName, rs.Size())
+		}
+		if strings.Contains(ec.Error(), root) {
+			t.Errorf("%v: errors must not contain the root directory, got %v", td.giveArgs, ec)
+		}
+	}
+}
+
+func TestCountStream(t *testing.T) {
+	testdata := []struct {
+		giveNames      []string
+		giveError      error
+		wontResultSize int
+		wontErrorSize  int
+	}{
+		{[]string{"testdata/wc/humpty_dumpty.txt", "testdata/archives/wc.tar"}, io.EOF, 5, 0},
+		{[]string{"testdata/wc/humpty_dumpty.txt"}, stderrors.New("broken stream"), 1, 1},
+	}
+	for _, td := range testdata {
+		index := NewOrder()
+		names := td.giveNames
+		rs, ec := NewWildcat(&ReadOptions{}, &RuntimeOptions{ThreadNumber: 1}, DefaultGenerator).CountStreamContext(context.Background(), func() (Entry, error) {
+			if len(names) == 0 {
+				return nil, td.giveError
+			}
+			entry := NewFileEntryWithIndex(NewArgWithIndex(index, names[0]))
+			names, index = names[1:], index.Next()
+			return entry, nil
+		})
+		if rs.Size() != td.wontResultSize || ec.Size() != td.wontErrorSize {
+			t.Errorf("%v: wont %d results and %d errors, got %d results and %v", td.giveNames, td.wontResultSize, td.wontErrorSize, rs.Size(), ec)
+		}
+	}
+}
+
+// legacyProgress implements only the methods of Progress, not ContextProgress.
+type legacyProgress struct {
+	Progress
+	targets int
+}
+
+func (lp *legacyProgress) UpdateTarget() {
+	lp.targets++
+	lp.Progress.UpdateTarget()
+}
+
+func TestCountWithLegacyProgress(t *testing.T) {
+	progress := &legacyProgress{Progress: NewProgress(false, 1)}
+	runtimeOpts := &RuntimeOptions{ThreadNumber: 1, ProgressFactory: func() Progress { return progress }}
+	wc := NewWildcat(&ReadOptions{}, runtimeOpts, DefaultGenerator)
+	rs := countWithTimeout(t, func() (*ResultSet, *errors.Center) {
+		return wc.CountAll(NewArgf([]string{"testdata/wc/humpty_dumpty.txt", "testdata/wc/ja/sakura_sakura.txt"}, &ReadOptions{}, runtimeOpts))
+	})
+	if rs.Size() != 2 {
+		t.Errorf("result size did not match, wont 2, got %d", rs.Size())
+	}
+	// the producer and the two files.
+	if progress.targets != 3 {
+		t.Errorf("UpdateTarget calls did not match, wont 3, got %d", progress.targets)
+	}
+}